@@ -16,6 +16,10 @@ var (
 	ErrPtOscStdout           = errors.New("migration: failed to get stdout of pt-online-schema-change")
 	ErrPtOscStderr           = errors.New("migration: failed to get stderr of pt-online-schema-change")
 	ErrPtOscUnexpectedStderr = errors.New("migration: pt-online-schema-change stderr not as expected")
+	ErrServerVersion         = errors.New("migration: couldn't get the version of the database server")
+	ErrDdlMethod             = errors.New("migration: unknown method for running the ddl natively")
+	ErrDataLossCheck         = errors.New("migration: checking for data the alter would lose didn't return as expected")
+	ErrColumnDefinitions     = errors.New("migration: getting column definitions from information_schema didn't return as expected")
 )
 
 type ErrQueryFailed struct {
@@ -47,3 +51,13 @@ func NewErrInvalidInsert(err error) ErrInvalidInsert {
 func (e ErrInvalidInsert) Error() string {
 	return fmt.Sprintf("migration: invalid final insert statement: %s", e.Err)
 }
+
+type ErrDataLoss struct {
+	DuplicateKeys int
+	NullValues    int
+}
+
+func (e ErrDataLoss) Error() string {
+	return fmt.Sprintf("migration: the alter would lose data (duplicated values for a new unique key: %d, NULLs in a "+
+		"new NOT NULL column: %d). Set the 'allow_data_loss' custom option to 'true' to run it anyway", e.DuplicateKeys, e.NullValues)
+}
@@ -19,6 +19,15 @@ import (
 
 const (
 	maxTableLength = 64
+	// suffix for the empty clone table used to test native ddl
+	ddlTestTableSuffix = "_ddltest"
+	// bounds for the queries that look for data an alter would lose. the
+	// timeout is in milliseconds
+	dataLossCheckLimit   = 1000
+	dataLossCheckTimeout = 60000
+	// how many rows to scan at a time when looking for values that
+	// a narrower column type would truncate
+	truncationCheckChunkSize = 10000
 )
 
 // These constants are defined to match with migration's types
@@ -43,11 +52,26 @@ const (
 	PauseStatus         = 11
 )
 
+// ways that an alter can be run. these are reported to the UI after
+// the prep step
+const (
+	PTOSC_METHOD   = "pt-osc"
+	INSTANT_METHOD = "instant"
+	INPLACE_METHOD = "inplace"
+)
+
 var (
 	MODE_TO_STRING = map[int]string{
 		TABLE_MODE: "TABLE",
 		VIEW_MODE:  "VIEW",
 	}
+
+	// the clause appended to an alter to make mysql run it natively. mysql
+	// errors out instead of falling back to copying if it can't honor these
+	NATIVE_METHOD_TO_CLAUSE = map[string]string{
+		INSTANT_METHOD: "ALGORITHM=INSTANT",
+		INPLACE_METHOD: "ALGORITHM=INPLACE, LOCK=NONE",
+	}
 )
 
 var (
@@ -55,6 +79,35 @@ var (
 	copyPercentageRegex = regexp.MustCompile("^(?i)Copying `.*`\\.`.*`: +([0-9]|[1-9][0-9]|100)% .*")
 	waitingRegex        = regexp.MustCompile("^(?i)Replica.*Waiting\\.$")
 	pausingRegex        = regexp.MustCompile("^(?i)Pausing because.*")
+	// pt-osc formats the time remaining as "MM:SS", "HH:MM:SS", or "D+HH:MM:SS"
+	copyRemainingRegex = regexp.MustCompile("^(?:([0-9]+)\\+)?(?:([0-9]+):)?([0-9]+):([0-9]+)$")
+
+	// regexes for picking apart ddl statements
+	alterPrefixRegex = regexp.MustCompile("^(?i)(ALTER\\s+TABLE\\s+.*?\\s+)")
+	// alters that can't be tried against a clone table, or that already
+	// pick their own algorithm
+	nonNativeAlterRegex = regexp.MustCompile("(?i)\\b(RENAME|ALGORITHM|LOCK)\\b")
+	serverVersionRegex  = regexp.MustCompile("^([0-9]+)\\.([0-9]+)\\.([0-9]+)")
+	uniqueKeySpecRegex  = regexp.MustCompile("^(?is)ADD\\s+(CONSTRAINT(\\s+[^\\s(]+)?\\s+)?(UNIQUE|PRIMARY\\s+KEY)\\b")
+	notNullSpecRegex    = regexp.MustCompile("^(?is)(MODIFY|CHANGE)\\s+(COLUMN\\s+)?(`(?:[^`]|``)+`|[^\\s`]+)\\s.*\\bNOT\\s+NULL\\b")
+	keyPartOrderRegex   = regexp.MustCompile("(?i)\\s+(ASC|DESC)$")
+	changeColumnRegex   = regexp.MustCompile("^(?is)(MODIFY|CHANGE)\\s+(COLUMN\\s+)?(`(?:[^`]|``)+`|[^\\s`]+)\\s+(.*)$")
+	columnNameRegex     = regexp.MustCompile("^(?s)(`(?:[^`]|``)+`|[^\\s`]+)\\s+(.*)$")
+	columnTypeRegex     = regexp.MustCompile("^(?i)([a-z]+)\\s*(\\(\\s*([0-9]+)\\s*(,\\s*[0-9]+\\s*)?\\))?(\\s+unsigned)?")
+
+	// how long to sleep between chunks when scanning a table, so that
+	// the scan doesn't hog the database
+	truncationCheckThrottle = 100 * time.Millisecond
+
+	// the size in bytes of each integer type
+	INTEGER_TYPE_TO_BYTES = map[string]uint{
+		"tinyint":   1,
+		"smallint":  2,
+		"mediumint": 3,
+		"int":       4,
+		"integer":   4,
+		"bigint":    8,
+	}
 
 	// client functions
 	newDbClient = dbclient.New
@@ -72,6 +125,10 @@ var (
 	MoveToBlackHole          = (*Migration).MoveToBlackHole
 	DropTriggers             = (*Migration).DropTriggers
 	CleanUp                  = (*Migration).CleanUp
+	ServerVersion            = (*Migration).ServerVersion
+	CountDataLoss            = (*Migration).countDataLoss
+	ColumnDefinitions        = (*Migration).columnDefinitions
+	PrimaryKeyColumns        = (*Migration).primaryKeyColumns
 	TimestampedTable         = timestampedTable
 )
 
@@ -88,6 +145,7 @@ type Migration struct {
 	FilesDir       string
 	StateFile      string
 	LogFile        string
+	PidFile        string
 	DbClient       dbclient.MysqlDB
 	RunType        int
 	Mode           int
@@ -95,6 +153,8 @@ type Migration struct {
 	EnableTrash    bool
 	PendingDropsDb string
 	CustomOptions  map[string]string
+	DdlMethod      string
+	TableRowsStart int
 }
 
 type TableStats struct {
@@ -103,6 +163,25 @@ type TableStats struct {
 	IndexSize string
 }
 
+// CopyProgress is how far along pt-osc is with copying rows to the new
+// table, as parsed from a line of its stderr. RowsPerSecond is estimated
+// from the number of rows the table had when the migration was prepped,
+// and is 0 if that isn't known.
+type CopyProgress struct {
+	Percent       int
+	Remaining     time.Duration
+	Elapsed       time.Duration
+	RowsPerSecond int
+}
+
+// ColumnType describes the type of a column, either as it is defined
+// in information_schema, or as it is defined in a ddl statement.
+type ColumnType struct {
+	DataType string
+	Length   int
+	Unsigned bool
+}
+
 // dbClient creates a mysql client that connects to a database host.
 func (migration *Migration) SetupDbClient(user, password, cert, key, rootCA string, port int) error {
 	tlsConfig := &dbclient.TlsConfig{}
@@ -184,6 +263,510 @@ func (migration *Migration) CollectTableStats() (*TableStats, error) {
 	return tableStats, nil
 }
 
+// AlterClause turns "Alter table t1 add column...." into "add column...".
+func (migration *Migration) AlterClause() string {
+	return alterPrefixRegex.ReplaceAllLiteralString(migration.DdlStatement, "")
+}
+
+// ServerVersion gets the version of mysql that a migration's database is
+// running (ex: "8.0.23-log").
+func (migration *Migration) ServerVersion() (string, error) {
+	response, err := RunReadQuery(migration, "SELECT VERSION() AS version")
+	if err != nil {
+		return "", err
+	}
+	if len(response["version"]) != 1 {
+		return "", ErrServerVersion
+	}
+	return response["version"][0], nil
+}
+
+// supportsInstantDdl returns whether or not a mysql version understands
+// ALGORITHM=INSTANT, which was added in 8.0.12.
+func supportsInstantDdl(version string) bool {
+	match := serverVersionRegex.FindStringSubmatch(version)
+	if match == nil || strings.Contains(version, "MariaDB") {
+		return false
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	patch, _ := strconv.Atoi(match[3])
+	if major != 8 {
+		return major > 8
+	}
+	if minor != 0 {
+		return minor > 0
+	}
+	return patch >= 12
+}
+
+// ddlTestTable returns the name of the empty clone table that is used
+// to test a migration's ddl
+func ddlTestTable(table string) string {
+	availChars := maxTableLength - len(ddlTestTableSuffix) - 1
+	if len(table) > availChars {
+		table = table[0:availChars]
+	}
+	return "_" + table + ddlTestTableSuffix
+}
+
+// RecommendDdlMethod figures out the cheapest way to run an alter. It creates
+// an empty clone of the migration's table and tries the alter against it with
+// ALGORITHM=INSTANT (if the server supports it), and then with ALGORITHM=INPLACE
+// and LOCK=NONE. The first one that mysql accepts is returned. If neither is
+// accepted, the alter needs to be run with pt-osc.
+func (migration *Migration) RecommendDdlMethod() (string, error) {
+	alterClause := migration.AlterClause()
+	if nonNativeAlterRegex.MatchString(alterClause) {
+		glog.Infof("mig_id=%d: alter can't be tested natively, so it will be run with pt-osc.", migration.Id)
+		return PTOSC_METHOD, nil
+	}
+
+	version, err := ServerVersion(migration)
+	if err != nil {
+		return "", err
+	}
+	methods := []string{INPLACE_METHOD}
+	if supportsInstantDdl(version) {
+		methods = []string{INSTANT_METHOD, INPLACE_METHOD}
+	}
+
+	// make an empty clone of the table, and make sure it gets cleaned up
+	testTable := QuoteIdentifier(ddlTestTable(migration.Table))
+	err = RunWriteQuery(migration, "DROP TABLE IF EXISTS "+testTable)
+	if err != nil {
+		return "", err
+	}
+	err = RunWriteQuery(migration, "CREATE TABLE "+testTable+" LIKE "+QuoteIdentifier(migration.Table))
+	if err != nil {
+		return "", err
+	}
+	defer RunWriteQuery(migration, "DROP TABLE IF EXISTS "+testTable)
+
+	for _, method := range methods {
+		query := "ALTER TABLE " + testTable + " " + alterClause + ", " + NATIVE_METHOD_TO_CLAUSE[method]
+		if RunWriteQuery(migration, query) == nil {
+			glog.Infof("mig_id=%d: mysql %s can run the alter with method '%s'.", migration.Id, version, method)
+			return method, nil
+		}
+	}
+	glog.Infof("mig_id=%d: mysql %s can't run the alter natively, so it will be run with pt-osc.", migration.Id, version)
+	return PTOSC_METHOD, nil
+}
+
+// RunNativeDdl runs an alter directly against the database, forcing mysql to
+// use the given native method instead of copying the table.
+func (migration *Migration) RunNativeDdl(method string) error {
+	clause, ok := NATIVE_METHOD_TO_CLAUSE[method]
+	if !ok {
+		return ErrDdlMethod
+	}
+	query := "ALTER TABLE " + QuoteIdentifier(migration.Table) + " " + migration.AlterClause() + ", " + clause
+	return RunWriteQuery(migration, query)
+}
+
+// alterSpecs splits an alter clause (ex: "add column c int, add index (a, b)")
+// into its individual specifications. Commas inside of parentheses and quotes
+// don't split anything.
+func alterSpecs(alterClause string) []string {
+	specs := []string{}
+	var quote rune
+	depth := 0
+	start := 0
+	for i, c := range alterClause {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			specs = append(specs, strings.TrimSpace(alterClause[start:i]))
+			start = i + 1
+		}
+	}
+	if spec := strings.TrimSpace(alterClause[start:]); spec != "" {
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// parenContents returns what is inside of the first set of parentheses
+// in a string, or "" if there aren't any.
+func parenContents(spec string) string {
+	start := strings.Index(spec, "(")
+	if start == -1 {
+		return ""
+	}
+	depth := 0
+	for i := start; i < len(spec); i++ {
+		switch spec[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return spec[start+1 : i]
+			}
+		}
+	}
+	return ""
+}
+
+// unquoteIdentifier strips the backticks off of an identifier that came from
+// a ddl statement
+func unquoteIdentifier(identifier string) string {
+	if len(identifier) >= 2 && strings.HasPrefix(identifier, "`") && strings.HasSuffix(identifier, "`") {
+		return strings.Replace(identifier[1:len(identifier)-1], "``", "`", -1)
+	}
+	return identifier
+}
+
+// keyPartExpression turns a key part from an index definition into something
+// that can be selected. ex: "`name`(10) DESC" becomes "LEFT(`name`, 10)".
+func keyPartExpression(keyPart string) string {
+	keyPart = keyPartOrderRegex.ReplaceAllString(strings.TrimSpace(keyPart), "")
+	// functional key parts are already an expression
+	if strings.HasPrefix(keyPart, "(") {
+		return keyPart
+	}
+	column := keyPart
+	prefixLength := ""
+	if strings.HasPrefix(keyPart, "`") {
+		end := 1
+		for end < len(keyPart) {
+			if keyPart[end] == '`' {
+				if end+1 < len(keyPart) && keyPart[end+1] == '`' {
+					end += 2
+					continue
+				}
+				break
+			}
+			end++
+		}
+		column = keyPart[:end+1]
+		prefixLength = parenContents(keyPart[end:])
+	} else if i := strings.Index(keyPart, "("); i != -1 {
+		column = keyPart[:i]
+		prefixLength = parenContents(keyPart[i:])
+	}
+	expression := QuoteIdentifier(unquoteIdentifier(strings.TrimSpace(column)))
+	if prefixLength != "" {
+		expression = "LEFT(" + expression + ", " + strings.TrimSpace(prefixLength) + ")"
+	}
+	return expression
+}
+
+// dataLossChecks picks apart a migration's alter to find the new unique keys
+// (as lists of key part expressions) and the columns that are becoming
+// NOT NULL. pt-osc copies rows with INSERT IGNORE, so duplicates for a new
+// unique key get dropped and NULLs in a NOT NULL column get converted.
+func (migration *Migration) dataLossChecks() (uniqueKeys [][]string, notNullColumns []string) {
+	for _, spec := range alterSpecs(migration.AlterClause()) {
+		if match := uniqueKeySpecRegex.FindStringSubmatch(spec); match != nil {
+			keyParts := []string{}
+			for _, keyPart := range alterSpecs(parenContents(spec)) {
+				keyParts = append(keyParts, keyPartExpression(keyPart))
+			}
+			if len(keyParts) == 0 {
+				continue
+			}
+			uniqueKeys = append(uniqueKeys, keyParts)
+			// primary key columns are implicitly NOT NULL
+			if strings.HasPrefix(strings.ToUpper(match[3]), "PRIMARY") {
+				for _, keyPart := range keyParts {
+					if !strings.HasPrefix(keyPart, "(") && !strings.HasPrefix(keyPart, "LEFT(") {
+						notNullColumns = append(notNullColumns, keyPart)
+					}
+				}
+			}
+		} else if match := notNullSpecRegex.FindStringSubmatch(spec); match != nil {
+			notNullColumns = append(notNullColumns, QuoteIdentifier(unquoteIdentifier(match[3])))
+		}
+	}
+	return
+}
+
+// countDataLoss runs a bounded query that returns a single count, and
+// parses the count
+func (migration *Migration) countDataLoss(query string) (int, error) {
+	response, err := RunReadQuery(migration, query)
+	if err != nil {
+		return 0, err
+	}
+	if len(response["count"]) != 1 {
+		return 0, ErrDataLossCheck
+	}
+	count, err := strconv.Atoi(response["count"][0])
+	if err != nil {
+		return 0, ErrDataLossCheck
+	}
+	return count, nil
+}
+
+// CheckForDataLoss looks for rows that would be lost or changed when pt-osc
+// copies a table for an alter that adds a UNIQUE/PRIMARY key or makes a column
+// NOT NULL. The queries are bounded, so the counts it reports stop at
+// dataLossCheckLimit per key/column. Returns an ErrDataLoss if anything
+// would be lost.
+func (migration *Migration) CheckForDataLoss() error {
+	uniqueKeys, notNullColumns := migration.dataLossChecks()
+	table := QuoteIdentifier(migration.Table)
+	hint := fmt.Sprintf("/*+ MAX_EXECUTION_TIME(%d) */", dataLossCheckTimeout)
+	dataLoss := ErrDataLoss{}
+
+	for _, keyParts := range uniqueKeys {
+		// NULLs never conflict in a unique key, so leave them out
+		conditions := []string{}
+		for _, keyPart := range keyParts {
+			conditions = append(conditions, keyPart+" IS NOT NULL")
+		}
+		query := fmt.Sprintf("SELECT %s COUNT(*) AS count FROM (SELECT 1 FROM %s WHERE %s GROUP BY %s "+
+			"HAVING COUNT(*) > 1 LIMIT %d) AS duplicate_keys", hint, table, strings.Join(conditions, " AND "),
+			strings.Join(keyParts, ", "), dataLossCheckLimit)
+		count, err := CountDataLoss(migration, query)
+		if err != nil {
+			return err
+		}
+		dataLoss.DuplicateKeys += count
+	}
+
+	for _, column := range notNullColumns {
+		query := fmt.Sprintf("SELECT %s COUNT(*) AS count FROM (SELECT 1 FROM %s WHERE %s IS NULL LIMIT %d) AS null_values",
+			hint, table, column, dataLossCheckLimit)
+		count, err := CountDataLoss(migration, query)
+		if err != nil {
+			return err
+		}
+		dataLoss.NullValues += count
+	}
+
+	if dataLoss.DuplicateKeys > 0 || dataLoss.NullValues > 0 {
+		return dataLoss
+	}
+	return nil
+}
+
+// parseColumnType parses the type at the start of a column definition
+// (ex: "varchar(10) NOT NULL" or "int(11) unsigned").
+func parseColumnType(definition string) (ColumnType, bool) {
+	match := columnTypeRegex.FindStringSubmatch(strings.TrimSpace(definition))
+	if match == nil {
+		return ColumnType{}, false
+	}
+	columnType := ColumnType{
+		DataType: strings.ToLower(match[1]),
+		Unsigned: match[5] != "",
+	}
+	if match[3] != "" {
+		columnType.Length, _ = strconv.Atoi(match[3])
+	}
+	return columnType, true
+}
+
+// integerRange returns the smallest and largest values an integer type
+// can hold, as strings that can be put in a query
+func integerRange(columnType ColumnType) (string, string) {
+	bits := 8 * INTEGER_TYPE_TO_BYTES[columnType.DataType]
+	if columnType.Unsigned {
+		return "0", strconv.FormatUint(uint64(1)<<(bits-1)<<1-1, 10)
+	}
+	return strconv.FormatInt(-1<<(bits-1), 10), strconv.FormatInt(1<<(bits-1)-1, 10)
+}
+
+// truncationCondition returns a condition that is true for rows with a value
+// that won't fit when a column is changed from oldType to newType, or "" if
+// the new type isn't narrower.
+func truncationCondition(column string, oldType, newType ColumnType) string {
+	oldBytes, oldIsInteger := INTEGER_TYPE_TO_BYTES[oldType.DataType]
+	newBytes, newIsInteger := INTEGER_TYPE_TO_BYTES[newType.DataType]
+	if oldIsInteger && newIsInteger {
+		// a signed type never holds all of the values of an unsigned type
+		// of the same size, and an unsigned type never holds negatives
+		widens := newBytes >= oldBytes
+		if oldType.Unsigned != newType.Unsigned {
+			widens = oldType.Unsigned && newBytes > oldBytes
+		}
+		if widens {
+			return ""
+		}
+		min, max := integerRange(newType)
+		return fmt.Sprintf("(%s < %s OR %s > %s)", column, min, column, max)
+	}
+
+	switch newType.DataType {
+	case "char", "varchar":
+		if oldType.Length > newType.Length {
+			return fmt.Sprintf("CHAR_LENGTH(%s) > %d", column, newType.Length)
+		}
+	case "binary", "varbinary":
+		if oldType.Length > newType.Length {
+			return fmt.Sprintf("LENGTH(%s) > %d", column, newType.Length)
+		}
+	}
+	return ""
+}
+
+// columnDefinitions gets the current type of each column in a migration's
+// table from information_schema. The keys are lowercase column names.
+func (migration *Migration) columnDefinitions() (map[string]ColumnType, error) {
+	query := "SELECT COLUMN_NAME, COLUMN_TYPE, CHARACTER_MAXIMUM_LENGTH FROM information_schema.columns " +
+		"WHERE table_schema=? AND table_name=?"
+	args := []interface{}{migration.Database, migration.Table}
+	response, err := RunReadQuery(migration, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	names := response["COLUMN_NAME"]
+	if len(response["COLUMN_TYPE"]) != len(names) || len(response["CHARACTER_MAXIMUM_LENGTH"]) != len(names) {
+		return nil, ErrColumnDefinitions
+	}
+
+	columns := make(map[string]ColumnType)
+	for i, name := range names {
+		columnType, ok := parseColumnType(response["COLUMN_TYPE"][i])
+		if !ok {
+			return nil, ErrColumnDefinitions
+		}
+		// text types don't have their length in the column type
+		if maxLength, err := strconv.Atoi(response["CHARACTER_MAXIMUM_LENGTH"][i]); err == nil {
+			columnType.Length = maxLength
+		}
+		columns[strings.ToLower(name)] = columnType
+	}
+	return columns, nil
+}
+
+// primaryKeyColumns gets the (quoted) columns of a migration table's
+// primary key, in order
+func (migration *Migration) primaryKeyColumns() ([]string, error) {
+	query := "SELECT COLUMN_NAME FROM information_schema.key_column_usage WHERE table_schema=? AND table_name=? " +
+		"AND constraint_name='PRIMARY' ORDER BY ORDINAL_POSITION"
+	args := []interface{}{migration.Database, migration.Table}
+	response, err := RunReadQuery(migration, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	columns := []string{}
+	for _, column := range response["COLUMN_NAME"] {
+		columns = append(columns, QuoteIdentifier(column))
+	}
+	return columns, nil
+}
+
+// truncationConditions compares the column types in a migration's alter with
+// the current ones, and returns a condition for each column that is being
+// narrowed
+func (migration *Migration) truncationConditions() ([]string, error) {
+	var currentColumns map[string]ColumnType
+	conditions := []string{}
+	for _, spec := range alterSpecs(migration.AlterClause()) {
+		match := changeColumnRegex.FindStringSubmatch(spec)
+		if match == nil {
+			continue
+		}
+		column := unquoteIdentifier(match[3])
+		definition := match[4]
+		// "CHANGE old_name new_name definition"
+		if strings.ToUpper(match[1]) == "CHANGE" {
+			nameMatch := columnNameRegex.FindStringSubmatch(definition)
+			if nameMatch == nil {
+				continue
+			}
+			definition = nameMatch[2]
+		}
+		newType, ok := parseColumnType(definition)
+		if !ok {
+			continue
+		}
+
+		if currentColumns == nil {
+			var err error
+			currentColumns, err = ColumnDefinitions(migration)
+			if err != nil {
+				return nil, err
+			}
+		}
+		oldType, ok := currentColumns[strings.ToLower(column)]
+		if !ok {
+			continue
+		}
+		if condition := truncationCondition(QuoteIdentifier(column), oldType, newType); condition != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+	return conditions, nil
+}
+
+// CountTruncatedRows counts the rows in a migration's table that have a value
+// that won't fit in a column the alter makes narrower (ex: shrinking a varchar,
+// or changing an int to a smallint). If the table has a single column primary
+// key, it is scanned in chunks with a pause between each one. Otherwise it is
+// scanned with a single, time limited query.
+func (migration *Migration) CountTruncatedRows() (int, error) {
+	conditions, err := migration.truncationConditions()
+	if err != nil || len(conditions) == 0 {
+		return 0, err
+	}
+	truncated := "(" + strings.Join(conditions, " OR ") + ")"
+	table := QuoteIdentifier(migration.Table)
+
+	primaryKey, err := PrimaryKeyColumns(migration)
+	if err != nil {
+		return 0, err
+	}
+	if len(primaryKey) != 1 {
+		query := fmt.Sprintf("SELECT /*+ MAX_EXECUTION_TIME(%d) */ COUNT(*) AS count FROM %s WHERE %s",
+			dataLossCheckTimeout, table, truncated)
+		return CountDataLoss(migration, query)
+	}
+
+	count := 0
+	var lastKey interface{}
+	for {
+		where := ""
+		args := []interface{}{}
+		if lastKey != nil {
+			where = " WHERE " + primaryKey[0] + " > ?"
+			args = append(args, lastKey)
+		}
+		query := fmt.Sprintf("SELECT MAX(pk) AS last_key, COUNT(*) AS row_count, SUM(truncated) AS count FROM "+
+			"(SELECT %s AS pk, IFNULL(%s, 0) AS truncated FROM %s%s ORDER BY %s LIMIT %d) AS chunk",
+			primaryKey[0], truncated, table, where, primaryKey[0], truncationCheckChunkSize)
+		response, err := RunReadQuery(migration, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		if len(response["last_key"]) != 1 || len(response["row_count"]) != 1 || len(response["count"]) != 1 {
+			return 0, ErrDataLossCheck
+		}
+		rowCount, err := strconv.Atoi(response["row_count"][0])
+		if err != nil {
+			return 0, ErrDataLossCheck
+		}
+		// SUM() is NULL for an empty chunk
+		if response["count"][0] != "" {
+			chunkCount, err := strconv.Atoi(response["count"][0])
+			if err != nil {
+				return 0, ErrDataLossCheck
+			}
+			count += chunkCount
+		}
+		if rowCount < truncationCheckChunkSize {
+			break
+		}
+		lastKey = response["last_key"][0]
+		time.Sleep(truncationCheckThrottle)
+	}
+	glog.Infof("mig_id=%d: %d rows have values that the alter would truncate.", migration.Id, count)
+	return count, nil
+}
+
 // ValidateFinalInsert validates the syntax of the final insert statement,
 // and starts/rolls back a trxn to verify that the insert won't fail.
 func (migration *Migration) ValidateFinalInsert() error {
@@ -230,15 +813,23 @@ func (migration *Migration) DryRunCreatesNew() error {
 	return DirectDrop(migration)
 }
 
+// QuoteIdentifier wraps a database, table, or trigger name in backticks so
+// that it can be used in a generated statement. Backticks inside the name
+// are escaped by doubling them, which is how mysql expects them.
+func QuoteIdentifier(identifier string) string {
+	return "`" + strings.Replace(identifier, "`", "``", -1) + "`"
+}
+
 // DirectDrop will drop a table/view directly on the database
 func (migration *Migration) DirectDrop() error {
-	dropQuery := "DROP " + MODE_TO_STRING[migration.Mode] + " " + migration.Table
+	dropQuery := "DROP " + MODE_TO_STRING[migration.Mode] + " " + QuoteIdentifier(migration.Table)
 	return RunWriteQuery(migration, dropQuery)
 }
 
 // swapTables renames two tables atomically.
 func (migration *Migration) swapTables(table1Source, table1Dest, table2Source, table2Dest string) error {
-	query := "RENAME TABLE " + table1Source + " TO " + table1Dest + ", " + table2Source + " TO " + table2Dest
+	query := "RENAME TABLE " + QuoteIdentifier(table1Source) + " TO " + QuoteIdentifier(table1Dest) + ", " +
+		QuoteIdentifier(table2Source) + " TO " + QuoteIdentifier(table2Dest)
 	return RunWriteQuery(migration, query)
 }
 
@@ -278,7 +869,7 @@ func (migration *Migration) DropTriggers(table string) error {
 
 	// loop through the triggers and drop each one
 	for _, triggerName := range response["trigger_name"] {
-		dropQuery := "DROP TRIGGER IF EXISTS `" + migration.Database + "`.`" + triggerName + "`"
+		dropQuery := "DROP TRIGGER IF EXISTS " + QuoteIdentifier(migration.Database) + "." + QuoteIdentifier(triggerName)
 		err := RunWriteQuery(migration, dropQuery)
 		if err != nil {
 			return err
@@ -289,8 +880,8 @@ func (migration *Migration) DropTriggers(table string) error {
 
 // MoveToPendingDrops moves a table to the pending_drops database
 func (migration *Migration) MoveToPendingDrops(sourceTable, destTable string) error {
-	query := "RENAME TABLE `" + migration.Database + "`.`" + sourceTable + "` TO `" + migration.PendingDropsDb +
-		"`.`" + destTable + "`"
+	query := "RENAME TABLE " + QuoteIdentifier(migration.Database) + "." + QuoteIdentifier(sourceTable) + " TO " +
+		QuoteIdentifier(migration.PendingDropsDb) + "." + QuoteIdentifier(destTable)
 	err := RunWriteQuery(migration, query)
 	if err != nil {
 		return err
@@ -300,7 +891,7 @@ func (migration *Migration) MoveToPendingDrops(sourceTable, destTable string) er
 
 // MoveToBlackhole drops the table
 func (migration *Migration) MoveToBlackHole(table string) error {
-	query := "DROP TABLE " + table
+	query := "DROP TABLE " + QuoteIdentifier(table)
 	return RunWriteQuery(migration, query)
 }
 
@@ -418,13 +1009,62 @@ func (migration *Migration) WatchMigrationStderr(stderrPipe io.Reader, errChan c
 	errChan <- nil
 }
 
+// parseCopyRemaining parses the time remaining from a pt-osc progress line
+func parseCopyRemaining(remaining string) (time.Duration, bool) {
+	match := copyRemainingRegex.FindStringSubmatch(remaining)
+	if match == nil {
+		return 0, false
+	}
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var duration time.Duration
+	for i, unit := range units {
+		if match[i+1] == "" {
+			continue
+		}
+		value, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return 0, false
+		}
+		duration += time.Duration(value) * unit
+	}
+	return duration, true
+}
+
+// parseCopyProgress parses a pt-osc progress line (ex:
+// "Copying `db`.`table`:  45% 03:12 remain") into a CopyProgress. elapsed
+// is how long the copy has been running for
+func (migration *Migration) parseCopyProgress(line string, elapsed time.Duration) (CopyProgress, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return CopyProgress{}, false
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(fields[2], "%"))
+	if err != nil {
+		return CopyProgress{}, false
+	}
+	progress := CopyProgress{Percent: percent, Elapsed: elapsed}
+	if len(fields) >= 4 {
+		if remaining, ok := parseCopyRemaining(fields[3]); ok {
+			progress.Remaining = remaining
+		} else {
+			glog.Errorf("mig_id=%d: couldn't get time remaining from '%s'. Continuing anyway", migration.Id, line)
+		}
+	}
+	if migration.TableRowsStart > 0 && elapsed >= time.Second {
+		rowsCopied := migration.TableRowsStart * percent / 100
+		progress.RowsPerSecond = int(float64(rowsCopied) / elapsed.Seconds())
+	}
+	return progress, true
+}
+
 // WatchMigrationCopyStderr scans stderr of a migration on the copy step,
-// and parses each line to get the % copied. It also checks for unexpected
-// output, and it logs each line to a file
-func (migration *Migration) WatchMigrationCopyStderr(stderrPipe io.Reader, copyPercentChan chan int, errChan chan error, ptOscLogChan chan string) {
+// and parses each line to get the progress of the copy. It also checks for
+// unexpected output, and it logs each line to a file
+func (migration *Migration) WatchMigrationCopyStderr(stderrPipe io.Reader, copyProgressChan chan CopyProgress, errChan chan error, ptOscLogChan chan string) {
 	scanner := bufio.NewScanner(stderrPipe)
 	var line string
 	var wasError bool
+	started := time.Now()
 
 	for scanner.Scan() {
 		line = scanner.Text()
@@ -433,16 +1073,14 @@ func (migration *Migration) WatchMigrationCopyStderr(stderrPipe io.Reader, copyP
 
 		copyPercentageMatch := copyPercentageRegex.MatchString(line)
 		if copyPercentageMatch {
-			if len(strings.Fields(line)) < 3 {
+			copyProgress, ok := migration.parseCopyProgress(line, time.Since(started))
+			if !ok {
 				glog.Errorf("mig_id=%d: couldn't get copy percentage from '%s'. Continuing anyway", migration.Id, line)
+				continue
 			}
-			copyPercentageS := strings.TrimSuffix(strings.Fields(line)[2], "%")
-			copyPercentage, err := strconv.Atoi(copyPercentageS)
-			if err != nil {
-				glog.Errorf("mig_id=%d: couldn't get int percentage from '%s'. Continuing anyway", migration.Id, line)
-			}
-			glog.Infof("mig_id=%d: updating migration with copy percentage of %d", migration.Id, copyPercentage)
-			copyPercentChan <- copyPercentage
+			glog.Infof("mig_id=%d: updating migration with copy percentage of %d (%s remaining)", migration.Id,
+				copyProgress.Percent, copyProgress.Remaining)
+			copyProgressChan <- copyProgress
 		}
 	}
 	if err := scanner.Err(); err != nil {
@@ -475,3 +1113,18 @@ func (migration *Migration) WriteStateFile(content []byte) error {
 	err := ioutil.WriteFile(migration.StateFile, content, 0777)
 	return err
 }
+
+// WritePidFile saves the pid of the pt-osc process running a migration, so
+// that it can be found again if the runner dies
+func (migration *Migration) WritePidFile(pid int) error {
+	return ioutil.WriteFile(migration.PidFile, []byte(strconv.Itoa(pid)), 0777)
+}
+
+// ReadPidFile gets the pid that was saved with WritePidFile
+func (migration *Migration) ReadPidFile() (int, error) {
+	data, err := ioutil.ReadFile(migration.PidFile)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/square/shift/runner/pkg/dbclient"
 	"github.com/square/shift/runner/pkg/testutils"
@@ -258,6 +259,607 @@ func TestCollectTableStats(t *testing.T) {
 	}
 }
 
+// tests for stripping the "alter table" prefix off of a ddl statement
+var alterClauseTests = []struct {
+	ddlStatement        string
+	expectedAlterClause string
+}{
+	{"alter table t1    drop column c", "drop column c"},
+	{"ALTER  TABLE `t1` ADD COLUMN c INT", "ADD COLUMN c INT"},
+}
+
+func TestAlterClause(t *testing.T) {
+	for _, tt := range alterClauseTests {
+		migration := &Migration{DdlStatement: tt.ddlStatement}
+
+		expectedAlterClause := tt.expectedAlterClause
+		actualAlterClause := migration.AlterClause()
+		if actualAlterClause != expectedAlterClause {
+			t.Errorf("alter clause = %v, want %v", actualAlterClause, expectedAlterClause)
+		}
+	}
+}
+
+// tests for getting the mysql version of a migration's database
+var serverVersionTests = []struct {
+	readQueryError  error
+	queryCol        map[string][]string
+	expectedVersion string
+	expectedError   error
+}{
+	// fail to run the query
+	{ErrQueryFailed{}, nil, "", ErrQueryFailed{}},
+	// query doesn't return a version
+	{nil, map[string][]string{"version": []string{}}, "", ErrServerVersion},
+	// succeed
+	{nil, map[string][]string{"version": []string{"8.0.23-log"}}, "8.0.23-log", nil},
+}
+
+func TestServerVersion(t *testing.T) {
+	for _, tt := range serverVersionTests {
+		migration := &Migration{}
+
+		RunReadQuery = func(*Migration, string, ...interface{}) (map[string][]string, error) {
+			return tt.queryCol, tt.readQueryError
+		}
+
+		actualVersion, actualError := migration.ServerVersion()
+		expectedError := tt.expectedError
+		if actualError != expectedError {
+			t.Errorf("error = %v, want %v", actualError, expectedError)
+		}
+
+		expectedVersion := tt.expectedVersion
+		if actualVersion != expectedVersion {
+			t.Errorf("version = %v, want %v", actualVersion, expectedVersion)
+		}
+	}
+}
+
+// tests for checking if a mysql version supports ALGORITHM=INSTANT
+var supportsInstantDdlTests = []struct {
+	version          string
+	expectedResponse bool
+}{
+	{"5.6.40-log", false},
+	{"5.7.31-34-log", false},
+	{"8.0.11", false},
+	{"8.0.12", true},
+	{"8.0.23-log", true},
+	{"8.1.0", true},
+	{"9.0.1", true},
+	{"10.4.12-MariaDB", false},
+	{"garbage", false},
+}
+
+func TestSupportsInstantDdl(t *testing.T) {
+	for _, tt := range supportsInstantDdlTests {
+		expectedResponse := tt.expectedResponse
+		actualResponse := supportsInstantDdl(tt.version)
+		if actualResponse != expectedResponse {
+			t.Errorf("%s supports instant = %v, want %v", tt.version, actualResponse, expectedResponse)
+		}
+	}
+}
+
+// tests for recommending a method to run an alter with
+var recommendDdlMethodTests = []struct {
+	ddlStatement        string
+	version             string
+	versionError        error
+	createError         error
+	failedMethods       []string
+	expectedMethod      string
+	expectedError       error
+	expectedWriteQuerys []string
+}{
+	// alter renames the table
+	{"alter table t1 rename to t2", "8.0.23", nil, nil, nil, PTOSC_METHOD, nil, nil},
+	// alter picks its own algorithm
+	{"alter table t1 add column c int, algorithm=copy", "8.0.23", nil, nil, nil, PTOSC_METHOD, nil, nil},
+	// fail to get the server version
+	{"alter table t1 add column c int", "", ErrServerVersion, nil, nil, "", ErrServerVersion, nil},
+	// fail to create the clone table
+	{"alter table t1 add column c int", "8.0.23", nil, ErrQueryFailed{}, nil, "", ErrQueryFailed{}, []string{
+		"DROP TABLE IF EXISTS `_t1_ddltest`",
+		"CREATE TABLE `_t1_ddltest` LIKE `t1`",
+	}},
+	// instant works
+	{"alter table t1 add column c int", "8.0.23", nil, nil, nil, INSTANT_METHOD, nil, []string{
+		"DROP TABLE IF EXISTS `_t1_ddltest`",
+		"CREATE TABLE `_t1_ddltest` LIKE `t1`",
+		"ALTER TABLE `_t1_ddltest` add column c int, ALGORITHM=INSTANT",
+		"DROP TABLE IF EXISTS `_t1_ddltest`",
+	}},
+	// instant doesn't work, inplace does
+	{"alter table t1 add index (c)", "8.0.23", nil, nil, []string{"ALGORITHM=INSTANT"}, INPLACE_METHOD, nil, []string{
+		"DROP TABLE IF EXISTS `_t1_ddltest`",
+		"CREATE TABLE `_t1_ddltest` LIKE `t1`",
+		"ALTER TABLE `_t1_ddltest` add index (c), ALGORITHM=INSTANT",
+		"ALTER TABLE `_t1_ddltest` add index (c), ALGORITHM=INPLACE, LOCK=NONE",
+		"DROP TABLE IF EXISTS `_t1_ddltest`",
+	}},
+	// instant isn't supported by the server, inplace works
+	{"alter table t1 add index (c)", "5.7.31-log", nil, nil, nil, INPLACE_METHOD, nil, []string{
+		"DROP TABLE IF EXISTS `_t1_ddltest`",
+		"CREATE TABLE `_t1_ddltest` LIKE `t1`",
+		"ALTER TABLE `_t1_ddltest` add index (c), ALGORITHM=INPLACE, LOCK=NONE",
+		"DROP TABLE IF EXISTS `_t1_ddltest`",
+	}},
+	// neither works
+	{"alter table t1 modify c bigint", "8.0.23", nil, nil, []string{"ALGORITHM=INSTANT", "ALGORITHM=INPLACE"}, PTOSC_METHOD, nil, []string{
+		"DROP TABLE IF EXISTS `_t1_ddltest`",
+		"CREATE TABLE `_t1_ddltest` LIKE `t1`",
+		"ALTER TABLE `_t1_ddltest` modify c bigint, ALGORITHM=INSTANT",
+		"ALTER TABLE `_t1_ddltest` modify c bigint, ALGORITHM=INPLACE, LOCK=NONE",
+		"DROP TABLE IF EXISTS `_t1_ddltest`",
+	}},
+}
+
+func TestRecommendDdlMethod(t *testing.T) {
+	for _, tt := range recommendDdlMethodTests {
+		migration := &Migration{Table: "t1", DdlStatement: tt.ddlStatement}
+
+		ServerVersion = func(*Migration) (string, error) {
+			return tt.version, tt.versionError
+		}
+		var actualWriteQuerys []string
+		RunWriteQuery = func(mig *Migration, query string, args ...interface{}) error {
+			actualWriteQuerys = append(actualWriteQuerys, query)
+			if strings.HasPrefix(query, "CREATE TABLE") {
+				return tt.createError
+			}
+			for _, failedMethod := range tt.failedMethods {
+				if strings.Contains(query, failedMethod) {
+					return ErrQueryFailed{}
+				}
+			}
+			return nil
+		}
+
+		actualMethod, actualError := migration.RecommendDdlMethod()
+		expectedError := tt.expectedError
+		switch actualError.(type) {
+		default:
+			if actualError != expectedError {
+				t.Errorf("error = %v, want %v", actualError, expectedError)
+			}
+		case ErrQueryFailed:
+			if _, ok := expectedError.(ErrQueryFailed); !ok {
+				t.Errorf("error = %v, want %v", actualError, expectedError)
+			}
+		}
+
+		expectedMethod := tt.expectedMethod
+		if actualMethod != expectedMethod {
+			t.Errorf("method = %v, want %v", actualMethod, expectedMethod)
+		}
+
+		expectedWriteQuerys := tt.expectedWriteQuerys
+		if !reflect.DeepEqual(actualWriteQuerys, expectedWriteQuerys) {
+			t.Errorf("write queries = %v, want %v", actualWriteQuerys, expectedWriteQuerys)
+		}
+	}
+}
+
+// tests for naming the clone table used to test ddl
+func TestDdlTestTable(t *testing.T) {
+	expectedTable := "_t1_ddltest"
+	actualTable := ddlTestTable("t1")
+	if actualTable != expectedTable {
+		t.Errorf("table = %v, want %v", actualTable, expectedTable)
+	}
+
+	// table name is too long
+	actualTable = ddlTestTable(strings.Repeat("a", maxTableLength))
+	if len(actualTable) != maxTableLength {
+		t.Errorf("table length = %v, want %v", len(actualTable), maxTableLength)
+	}
+}
+
+// tests for running an alter natively
+var runNativeDdlTests = []struct {
+	method             string
+	writeQueryError    error
+	expectedError      error
+	expectedWriteQuery string
+}{
+	// unknown method
+	{PTOSC_METHOD, nil, ErrDdlMethod, ""},
+	// fail to run the query
+	{INSTANT_METHOD, ErrQueryFailed{}, ErrQueryFailed{}, "ALTER TABLE `t1` add column c int, ALGORITHM=INSTANT"},
+	// succeed instant
+	{INSTANT_METHOD, nil, nil, "ALTER TABLE `t1` add column c int, ALGORITHM=INSTANT"},
+	// succeed inplace
+	{INPLACE_METHOD, nil, nil, "ALTER TABLE `t1` add column c int, ALGORITHM=INPLACE, LOCK=NONE"},
+}
+
+func TestRunNativeDdl(t *testing.T) {
+	for _, tt := range runNativeDdlTests {
+		migration := &Migration{Table: "t1", DdlStatement: "ALTER TABLE t1 add column c int"}
+
+		var actualWriteQuery string
+		RunWriteQuery = func(mig *Migration, query string, args ...interface{}) error {
+			actualWriteQuery = query
+			return tt.writeQueryError
+		}
+
+		actualError := migration.RunNativeDdl(tt.method)
+		expectedError := tt.expectedError
+		if actualError != expectedError {
+			t.Errorf("error = %v, want %v", actualError, expectedError)
+		}
+
+		expectedWriteQuery := tt.expectedWriteQuery
+		if actualWriteQuery != expectedWriteQuery {
+			t.Errorf("query = %v, want %v", actualWriteQuery, expectedWriteQuery)
+		}
+	}
+}
+
+// tests for splitting an alter clause into specifications
+var alterSpecsTests = []struct {
+	alterClause   string
+	expectedSpecs []string
+}{
+	{"", []string{}},
+	{"add column c int", []string{"add column c int"}},
+	{"add column c decimal(10, 2), add unique index (a, b)", []string{"add column c decimal(10, 2)", "add unique index (a, b)"}},
+	{"add column c varchar(10) default 'x,y' , drop column `d,e`", []string{"add column c varchar(10) default 'x,y'", "drop column `d,e`"}},
+}
+
+func TestAlterSpecs(t *testing.T) {
+	for _, tt := range alterSpecsTests {
+		expectedSpecs := tt.expectedSpecs
+		actualSpecs := alterSpecs(tt.alterClause)
+		if !reflect.DeepEqual(actualSpecs, expectedSpecs) {
+			t.Errorf("specs = %v, want %v", actualSpecs, expectedSpecs)
+		}
+	}
+}
+
+// tests for finding the unique keys and NOT NULL columns an alter adds
+var dataLossChecksTests = []struct {
+	ddlStatement           string
+	expectedUniqueKeys     [][]string
+	expectedNotNullColumns []string
+}{
+	// nothing to check
+	{"alter table t1 add column c int not null default 0, add index (a)", nil, nil},
+	// unique index
+	{"alter table t1 add unique index idx_a_b (a, `b`)", [][]string{[]string{"`a`", "`b`"}}, nil},
+	// unique key with a prefix length, sort order and constraint name
+	{"ALTER TABLE t1 ADD CONSTRAINT uc UNIQUE KEY (`name`(10) DESC, email)",
+		[][]string{[]string{"LEFT(`name`, 10)", "`email`"}}, nil},
+	// unique constraint without a name
+	{"alter table t1 add constraint unique (a)", [][]string{[]string{"`a`"}}, nil},
+	// primary key is also NOT NULL
+	{"alter table t1 drop primary key, add primary key (id, a)", [][]string{[]string{"`id`", "`a`"}}, []string{"`id`", "`a`"}},
+	// modify and change to NOT NULL
+	{"alter table t1 modify c int NOT NULL, change column `d` e varchar(10) not null, modify f int null",
+		nil, []string{"`c`", "`d`"}},
+}
+
+func TestDataLossChecks(t *testing.T) {
+	for _, tt := range dataLossChecksTests {
+		migration := &Migration{DdlStatement: tt.ddlStatement}
+
+		actualUniqueKeys, actualNotNullColumns := migration.dataLossChecks()
+		expectedUniqueKeys := tt.expectedUniqueKeys
+		if !reflect.DeepEqual(actualUniqueKeys, expectedUniqueKeys) {
+			t.Errorf("unique keys = %v, want %v", actualUniqueKeys, expectedUniqueKeys)
+		}
+
+		expectedNotNullColumns := tt.expectedNotNullColumns
+		if !reflect.DeepEqual(actualNotNullColumns, expectedNotNullColumns) {
+			t.Errorf("not null columns = %v, want %v", actualNotNullColumns, expectedNotNullColumns)
+		}
+	}
+}
+
+// tests for checking if an alter would lose data
+var checkForDataLossTests = []struct {
+	ddlStatement   string
+	counts         map[string]int
+	countError     error
+	expectedError  error
+	expectedQuerys []string
+}{
+	// nothing to check
+	{"alter table t1 add column c int", nil, nil, nil, nil},
+	// fail to run a query
+	{"alter table t1 add unique (a)", nil, ErrDataLossCheck, ErrDataLossCheck, []string{
+		"SELECT /*+ MAX_EXECUTION_TIME(60000) */ COUNT(*) AS count FROM (SELECT 1 FROM `t1` WHERE `a` IS NOT NULL " +
+			"GROUP BY `a` HAVING COUNT(*) > 1 LIMIT 1000) AS duplicate_keys",
+	}},
+	// no duplicates or NULLs
+	{"alter table t1 add unique (a, b), modify c int not null", nil, nil, nil, []string{
+		"SELECT /*+ MAX_EXECUTION_TIME(60000) */ COUNT(*) AS count FROM (SELECT 1 FROM `t1` WHERE `a` IS NOT NULL " +
+			"AND `b` IS NOT NULL GROUP BY `a`, `b` HAVING COUNT(*) > 1 LIMIT 1000) AS duplicate_keys",
+		"SELECT /*+ MAX_EXECUTION_TIME(60000) */ COUNT(*) AS count FROM (SELECT 1 FROM `t1` WHERE `c` IS NULL " +
+			"LIMIT 1000) AS null_values",
+	}},
+	// duplicates and NULLs
+	{"alter table t1 add unique (a), modify c int not null", map[string]int{"duplicate_keys": 4, "null_values": 7}, nil,
+		ErrDataLoss{DuplicateKeys: 4, NullValues: 7}, []string{
+			"SELECT /*+ MAX_EXECUTION_TIME(60000) */ COUNT(*) AS count FROM (SELECT 1 FROM `t1` WHERE `a` IS NOT NULL " +
+				"GROUP BY `a` HAVING COUNT(*) > 1 LIMIT 1000) AS duplicate_keys",
+			"SELECT /*+ MAX_EXECUTION_TIME(60000) */ COUNT(*) AS count FROM (SELECT 1 FROM `t1` WHERE `c` IS NULL " +
+				"LIMIT 1000) AS null_values",
+		}},
+}
+
+func TestCheckForDataLoss(t *testing.T) {
+	for _, tt := range checkForDataLossTests {
+		migration := &Migration{Table: "t1", DdlStatement: tt.ddlStatement}
+
+		var actualQuerys []string
+		CountDataLoss = func(mig *Migration, query string) (int, error) {
+			actualQuerys = append(actualQuerys, query)
+			for alias, count := range tt.counts {
+				if strings.HasSuffix(query, alias) {
+					return count, nil
+				}
+			}
+			return 0, tt.countError
+		}
+
+		actualError := migration.CheckForDataLoss()
+		expectedError := tt.expectedError
+		if actualError != expectedError {
+			t.Errorf("error = %v, want %v", actualError, expectedError)
+		}
+
+		expectedQuerys := tt.expectedQuerys
+		if !reflect.DeepEqual(actualQuerys, expectedQuerys) {
+			t.Errorf("queries = %v, want %v", actualQuerys, expectedQuerys)
+		}
+	}
+}
+
+// tests for running a bounded count query
+var countDataLossTests = []struct {
+	readQueryError error
+	queryCol       map[string][]string
+	expectedCount  int
+	expectedError  error
+}{
+	// fail to run the query
+	{ErrQueryFailed{}, nil, 0, ErrQueryFailed{}},
+	// no count returned
+	{nil, map[string][]string{"count": []string{}}, 0, ErrDataLossCheck},
+	// count isn't a number
+	{nil, map[string][]string{"count": []string{"abc"}}, 0, ErrDataLossCheck},
+	// succeed
+	{nil, map[string][]string{"count": []string{"12"}}, 12, nil},
+}
+
+func TestCountDataLoss(t *testing.T) {
+	for _, tt := range countDataLossTests {
+		migration := &Migration{}
+		RunReadQuery = func(*Migration, string, ...interface{}) (map[string][]string, error) {
+			return tt.queryCol, tt.readQueryError
+		}
+
+		actualCount, actualError := migration.countDataLoss("select count")
+		expectedError := tt.expectedError
+		if actualError != expectedError {
+			t.Errorf("error = %v, want %v", actualError, expectedError)
+		}
+
+		expectedCount := tt.expectedCount
+		if actualCount != expectedCount {
+			t.Errorf("count = %v, want %v", actualCount, expectedCount)
+		}
+	}
+}
+
+// tests for parsing a column type
+var parseColumnTypeTests = []struct {
+	definition   string
+	expectedType ColumnType
+	expectedOk   bool
+}{
+	{"varchar(10) NOT NULL", ColumnType{"varchar", 10, false}, true},
+	{"int(11) unsigned", ColumnType{"int", 11, true}, true},
+	{"  SMALLINT DEFAULT 0", ColumnType{"smallint", 0, false}, true},
+	{"decimal(10, 2) unsigned", ColumnType{"decimal", 10, true}, true},
+	{"(bad)", ColumnType{}, false},
+}
+
+func TestParseColumnType(t *testing.T) {
+	for _, tt := range parseColumnTypeTests {
+		actualType, actualOk := parseColumnType(tt.definition)
+		expectedType := tt.expectedType
+		if actualType != expectedType {
+			t.Errorf("type = %v, want %v", actualType, expectedType)
+		}
+
+		expectedOk := tt.expectedOk
+		if actualOk != expectedOk {
+			t.Errorf("ok = %v, want %v", actualOk, expectedOk)
+		}
+	}
+}
+
+// tests for building the condition for values a narrower type would truncate
+var truncationConditionTests = []struct {
+	oldType           ColumnType
+	newType           ColumnType
+	expectedCondition string
+}{
+	// widening an int
+	{ColumnType{"int", 11, false}, ColumnType{"bigint", 20, false}, ""},
+	// narrowing an int
+	{ColumnType{"int", 11, false}, ColumnType{"smallint", 6, false}, "(`c` < -32768 OR `c` > 32767)"},
+	// signed to unsigned of the same size
+	{ColumnType{"tinyint", 4, false}, ColumnType{"tinyint", 3, true}, "(`c` < 0 OR `c` > 255)"},
+	// unsigned to signed of the same size
+	{ColumnType{"bigint", 20, true}, ColumnType{"bigint", 20, false},
+		"(`c` < -9223372036854775808 OR `c` > 9223372036854775807)"},
+	// unsigned to a bigger signed type
+	{ColumnType{"int", 10, true}, ColumnType{"bigint", 20, false}, ""},
+	// signed to a bigger unsigned type
+	{ColumnType{"int", 11, false}, ColumnType{"bigint", 20, true}, "(`c` < 0 OR `c` > 18446744073709551615)"},
+	// shrinking a varchar
+	{ColumnType{"varchar", 255, false}, ColumnType{"varchar", 64, false}, "CHAR_LENGTH(`c`) > 64"},
+	// text to a varchar
+	{ColumnType{"text", 65535, false}, ColumnType{"varchar", 255, false}, "CHAR_LENGTH(`c`) > 255"},
+	// growing a varchar
+	{ColumnType{"varchar", 64, false}, ColumnType{"varchar", 255, false}, ""},
+	// shrinking a varbinary
+	{ColumnType{"varbinary", 32, false}, ColumnType{"binary", 16, false}, "LENGTH(`c`) > 16"},
+	// a type that isn't checked
+	{ColumnType{"datetime", 0, false}, ColumnType{"date", 0, false}, ""},
+}
+
+func TestTruncationCondition(t *testing.T) {
+	for _, tt := range truncationConditionTests {
+		actualCondition := truncationCondition("`c`", tt.oldType, tt.newType)
+		expectedCondition := tt.expectedCondition
+		if actualCondition != expectedCondition {
+			t.Errorf("condition = %v, want %v", actualCondition, expectedCondition)
+		}
+	}
+}
+
+// tests for getting the current column definitions
+var columnDefinitionsTests = []struct {
+	readQueryError  error
+	queryCol        map[string][]string
+	expectedColumns map[string]ColumnType
+	expectedError   error
+}{
+	// fail to run the query
+	{ErrQueryFailed{}, nil, nil, ErrQueryFailed{}},
+	// columns don't line up
+	{nil, map[string][]string{"COLUMN_NAME": []string{"a"}, "COLUMN_TYPE": []string{},
+		"CHARACTER_MAXIMUM_LENGTH": []string{""}}, nil, ErrColumnDefinitions},
+	// can't parse a column type
+	{nil, map[string][]string{"COLUMN_NAME": []string{"a"}, "COLUMN_TYPE": []string{"(bad)"},
+		"CHARACTER_MAXIMUM_LENGTH": []string{""}}, nil, ErrColumnDefinitions},
+	// succeed
+	{nil, map[string][]string{"COLUMN_NAME": []string{"Id", "body"}, "COLUMN_TYPE": []string{"int(10) unsigned", "text"},
+		"CHARACTER_MAXIMUM_LENGTH": []string{"", "65535"}}, map[string]ColumnType{
+		"id":   ColumnType{"int", 10, true},
+		"body": ColumnType{"text", 65535, false},
+	}, nil},
+}
+
+func TestColumnDefinitions(t *testing.T) {
+	for _, tt := range columnDefinitionsTests {
+		migration := &Migration{Database: "db1", Table: "t1"}
+		RunReadQuery = func(*Migration, string, ...interface{}) (map[string][]string, error) {
+			return tt.queryCol, tt.readQueryError
+		}
+
+		actualColumns, actualError := migration.columnDefinitions()
+		expectedError := tt.expectedError
+		if actualError != expectedError {
+			t.Errorf("error = %v, want %v", actualError, expectedError)
+		}
+
+		expectedColumns := tt.expectedColumns
+		if !reflect.DeepEqual(actualColumns, expectedColumns) {
+			t.Errorf("columns = %v, want %v", actualColumns, expectedColumns)
+		}
+	}
+}
+
+// tests for counting the rows a narrower column type would truncate
+var countTruncatedRowsTests = []struct {
+	ddlStatement   string
+	primaryKey     []string
+	chunks         []map[string][]string
+	readQueryError error
+	expectedCount  int
+	expectedError  error
+	expectedQuerys []string
+}{
+	// nothing is narrowed
+	{"alter table t1 modify a bigint, add column d int", []string{"`id`"}, nil, nil, 0, nil, nil},
+	// no single column primary key, so use one bounded query
+	{"alter table t1 modify a smallint", []string{}, nil, nil, 0, nil, []string{
+		"SELECT /*+ MAX_EXECUTION_TIME(60000) */ COUNT(*) AS count FROM `t1` WHERE ((`a` < -32768 OR `a` > 32767))",
+	}},
+	// fail to scan a chunk
+	{"alter table t1 change column b b varchar(10)", []string{"`id`"}, nil, ErrQueryFailed{}, 0, ErrQueryFailed{}, []string{
+		"SELECT MAX(pk) AS last_key, COUNT(*) AS row_count, SUM(truncated) AS count FROM (SELECT `id` AS pk, " +
+			"IFNULL((CHAR_LENGTH(`b`) > 10), 0) AS truncated FROM `t1` ORDER BY `id` LIMIT 10000) AS chunk",
+	}},
+	// bad response scanning a chunk
+	{"alter table t1 modify a smallint", []string{"`id`"}, []map[string][]string{
+		{"last_key": []string{""}, "row_count": []string{"abc"}, "count": []string{""}},
+	}, nil, 0, ErrDataLossCheck, []string{
+		"SELECT MAX(pk) AS last_key, COUNT(*) AS row_count, SUM(truncated) AS count FROM (SELECT `id` AS pk, " +
+			"IFNULL(((`a` < -32768 OR `a` > 32767)), 0) AS truncated FROM `t1` ORDER BY `id` LIMIT 10000) AS chunk",
+	}},
+	// scan a table in two chunks
+	{"alter table t1 modify a smallint, change `b` `c` varchar(10) not null", []string{"`id`"}, []map[string][]string{
+		{"last_key": []string{"10000"}, "row_count": []string{"10000"}, "count": []string{"4"}},
+		{"last_key": []string{"10003"}, "row_count": []string{"3"}, "count": []string{"1"}},
+	}, nil, 5, nil, []string{
+		"SELECT MAX(pk) AS last_key, COUNT(*) AS row_count, SUM(truncated) AS count FROM (SELECT `id` AS pk, " +
+			"IFNULL(((`a` < -32768 OR `a` > 32767) OR CHAR_LENGTH(`b`) > 10), 0) AS truncated FROM `t1` " +
+			"ORDER BY `id` LIMIT 10000) AS chunk",
+		"SELECT MAX(pk) AS last_key, COUNT(*) AS row_count, SUM(truncated) AS count FROM (SELECT `id` AS pk, " +
+			"IFNULL(((`a` < -32768 OR `a` > 32767) OR CHAR_LENGTH(`b`) > 10), 0) AS truncated FROM `t1` " +
+			"WHERE `id` > ? ORDER BY `id` LIMIT 10000) AS chunk",
+	}},
+	// empty table
+	{"alter table t1 modify a smallint", []string{"`id`"}, []map[string][]string{
+		{"last_key": []string{""}, "row_count": []string{"0"}, "count": []string{""}},
+	}, nil, 0, nil, []string{
+		"SELECT MAX(pk) AS last_key, COUNT(*) AS row_count, SUM(truncated) AS count FROM (SELECT `id` AS pk, " +
+			"IFNULL(((`a` < -32768 OR `a` > 32767)), 0) AS truncated FROM `t1` ORDER BY `id` LIMIT 10000) AS chunk",
+	}},
+}
+
+func TestCountTruncatedRows(t *testing.T) {
+	truncationCheckThrottle = 0
+	for _, tt := range countTruncatedRowsTests {
+		migration := &Migration{Table: "t1", DdlStatement: tt.ddlStatement}
+		ColumnDefinitions = func(*Migration) (map[string]ColumnType, error) {
+			return map[string]ColumnType{
+				"a": ColumnType{"int", 11, false},
+				"b": ColumnType{"varchar", 255, false},
+			}, nil
+		}
+		PrimaryKeyColumns = func(*Migration) ([]string, error) {
+			return tt.primaryKey, nil
+		}
+
+		var actualQuerys []string
+		CountDataLoss = func(mig *Migration, query string) (int, error) {
+			actualQuerys = append(actualQuerys, query)
+			return 0, nil
+		}
+		RunReadQuery = func(mig *Migration, query string, args ...interface{}) (map[string][]string, error) {
+			actualQuerys = append(actualQuerys, query)
+			if tt.readQueryError != nil {
+				return nil, tt.readQueryError
+			}
+			return tt.chunks[len(actualQuerys)-1], nil
+		}
+
+		actualCount, actualError := migration.CountTruncatedRows()
+		expectedError := tt.expectedError
+		if actualError != expectedError {
+			t.Errorf("error = %v, want %v", actualError, expectedError)
+		}
+
+		expectedCount := tt.expectedCount
+		if actualCount != expectedCount {
+			t.Errorf("count = %v, want %v", actualCount, expectedCount)
+		}
+
+		expectedQuerys := tt.expectedQuerys
+		if !reflect.DeepEqual(actualQuerys, expectedQuerys) {
+			t.Errorf("queries = %v, want %v", actualQuerys, expectedQuerys)
+		}
+	}
+}
+
 // tests for running a dry run of creating a new table/view
 var dryRunCreatesNewTests = []struct {
 	readQueryError  error
@@ -312,11 +914,15 @@ var directDropTests = []struct {
 	expectedWriteQuery string
 }{
 	// fail on write query
-	{"a", SHORT_RUN, DROP_ACTION, TABLE_MODE, ErrQueryFailed{}, ErrQueryFailed{}, "DROP TABLE a"},
+	{"a", SHORT_RUN, DROP_ACTION, TABLE_MODE, ErrQueryFailed{}, ErrQueryFailed{}, "DROP TABLE `a`"},
 	// succeed drop table
-	{"b", SHORT_RUN, DROP_ACTION, TABLE_MODE, nil, nil, "DROP TABLE b"},
+	{"b", SHORT_RUN, DROP_ACTION, TABLE_MODE, nil, nil, "DROP TABLE `b`"},
 	// succeed drop view
-	{"c", SHORT_RUN, CREATE_ACTION, VIEW_MODE, nil, nil, "DROP VIEW c"},
+	{"c", SHORT_RUN, CREATE_ACTION, VIEW_MODE, nil, nil, "DROP VIEW `c`"},
+	// succeed drop table named with a reserved word
+	{"order", SHORT_RUN, DROP_ACTION, TABLE_MODE, nil, nil, "DROP TABLE `order`"},
+	// succeed drop table with a backtick in its name
+	{"a`; DROP TABLE b; --", SHORT_RUN, DROP_ACTION, TABLE_MODE, nil, nil, "DROP TABLE `a``; DROP TABLE b; --`"},
 }
 
 func TestDirectDrop(t *testing.T) {
@@ -358,13 +964,20 @@ func TestDirectDrop(t *testing.T) {
 
 // tests for renaming two tables
 var swapTablesTests = []struct {
-	writeQueryError error
-	expectedError   error
+	tables             []string
+	writeQueryError    error
+	expectedError      error
+	expectedWriteQuery string
 }{
 	// query failed
-	{ErrQueryFailed{}, ErrQueryFailed{}},
+	{[]string{"t1s", "t1d", "t2s", "t2d"}, ErrQueryFailed{}, ErrQueryFailed{},
+		"RENAME TABLE `t1s` TO `t1d`, `t2s` TO `t2d`"},
 	// success
-	{nil, nil},
+	{[]string{"t1s", "t1d", "t2s", "t2d"}, nil, nil,
+		"RENAME TABLE `t1s` TO `t1d`, `t2s` TO `t2d`"},
+	// success with reserved words, unicode and backticks
+	{[]string{"select", "20150826_select", "_tåble_new", "t`ble"}, nil, nil,
+		"RENAME TABLE `select` TO `20150826_select`, `_tåble_new` TO `t``ble`"},
 }
 
 func TestSwapTables(t *testing.T) {
@@ -372,15 +985,22 @@ func TestSwapTables(t *testing.T) {
 		StubDbClient := &testUtils.StubDbClient{}
 		migration := &Migration{DbClient: StubDbClient}
 
+		var actualWriteQuery string
 		RunWriteQuery = func(mig *Migration, query string, args ...interface{}) error {
+			actualWriteQuery = query
 			return tt.writeQueryError
 		}
 
-		actualError := migration.swapTables("t1s", "t1d", "t2s", "t2d")
+		actualError := migration.swapTables(tt.tables[0], tt.tables[1], tt.tables[2], tt.tables[3])
 		expectedError := tt.expectedError
 		if actualError != expectedError {
 			t.Errorf("error = %v, want %v", actualError, expectedError)
 		}
+
+		expectedWriteQuery := tt.expectedWriteQuery
+		if actualWriteQuery != expectedWriteQuery {
+			t.Errorf("query = %v, want %v", actualWriteQuery, expectedWriteQuery)
+		}
 	}
 }
 
@@ -454,6 +1074,8 @@ var dropTriggersTests = []struct {
 	{nil, ErrQueryFailed{}, map[string][]string{"trigger_name": []string{"t1"}}, ErrQueryFailed{}, "DROP TRIGGER IF EXISTS `db`.`t1`"},
 	// successfully drop a trigger
 	{nil, nil, map[string][]string{"trigger_name": []string{"t1"}}, nil, "DROP TRIGGER IF EXISTS `db`.`t1`"},
+	// successfully drop a trigger with a backtick in its name
+	{nil, nil, map[string][]string{"trigger_name": []string{"t`1"}}, nil, "DROP TRIGGER IF EXISTS `db`.`t``1`"},
 }
 
 func TestDropTriggers(t *testing.T) {
@@ -494,6 +1116,10 @@ var moveToPendingDropsTests = []struct {
 	{"table1", "table1_old", ErrQueryFailed{}, ErrQueryFailed{}},
 	// succeed
 	{"table", "table1_old", nil, nil},
+	// succeed with a reserved word
+	{"group", "20150826_group", nil, nil},
+	// succeed with unicode
+	{"tàbleé", "20150826_tàbleé", nil, nil},
 }
 
 func TestMoveToPendingDrops(t *testing.T) {
@@ -521,6 +1147,73 @@ func TestMoveToPendingDrops(t *testing.T) {
 	}
 }
 
+// tests for quoting identifiers in generated statements
+var quoteIdentifierTests = []struct {
+	identifier         string
+	expectedIdentifier string
+}{
+	{"table1", "`table1`"},
+	// reserved words
+	{"order", "`order`"},
+	{"select", "`select`"},
+	// unicode
+	{"tàble_名前", "`tàble_名前`"},
+	// backticks get doubled
+	{"tab`le", "`tab``le`"},
+	{"``", "``````"},
+	// attempted injection stays inside the identifier
+	{"t` ; DROP DATABASE db; -- ", "`t`` ; DROP DATABASE db; -- `"},
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	for _, tt := range quoteIdentifierTests {
+		expectedIdentifier := tt.expectedIdentifier
+		actualIdentifier := QuoteIdentifier(tt.identifier)
+		if actualIdentifier != expectedIdentifier {
+			t.Errorf("identifier = %v, want %v", actualIdentifier, expectedIdentifier)
+		}
+	}
+}
+
+// tests for dropping a table outright
+var moveToBlackHoleTests = []struct {
+	table              string
+	writeQueryError    error
+	expectedError      error
+	expectedWriteQuery string
+}{
+	// fail to run the query
+	{"_t1_new", ErrQueryFailed{}, ErrQueryFailed{}, "DROP TABLE `_t1_new`"},
+	// succeed
+	{"_t1_new", nil, nil, "DROP TABLE `_t1_new`"},
+	// succeed with a backtick in the table name
+	{"_t`1_new", nil, nil, "DROP TABLE `_t``1_new`"},
+}
+
+func TestMoveToBlackHole(t *testing.T) {
+	for _, tt := range moveToBlackHoleTests {
+		StubDbClient := &testUtils.StubDbClient{}
+		migration := &Migration{DbClient: StubDbClient, Database: "db"}
+
+		var actualWriteQuery string
+		RunWriteQuery = func(mig *Migration, query string, args ...interface{}) error {
+			actualWriteQuery = query
+			return tt.writeQueryError
+		}
+
+		actualError := migration.MoveToBlackHole(tt.table)
+		expectedError := tt.expectedError
+		if actualError != expectedError {
+			t.Errorf("error = %v, want %v", actualError, expectedError)
+		}
+
+		expectedWriteQuery := tt.expectedWriteQuery
+		if actualWriteQuery != expectedWriteQuery {
+			t.Errorf("query = %v, want %v", actualWriteQuery, expectedWriteQuery)
+		}
+	}
+}
+
 // tests for cleaning up after a migration
 var cleanUpTests = []struct {
 	dropTriggersError error
@@ -712,6 +1405,48 @@ func TestWatchMigrationOutput(t *testing.T) {
 	}
 }
 
+// tests for parsing the progress of a pt-osc copy
+var parseCopyProgressTests = []struct {
+	line             string
+	tableRowsStart   int
+	elapsed          time.Duration
+	expectedProgress CopyProgress
+	expectedOk       bool
+}{
+	// minutes and seconds remaining
+	{"Copying `db`.`table`:  45% 03:12 remain", 0, 157 * time.Second,
+		CopyProgress{45, 192 * time.Second, 157 * time.Second, 0}, true},
+	// hours remaining, with a throughput
+	{"Copying `db`.`table`:   6% 01:04:21 remain", 1000000, 200 * time.Second,
+		CopyProgress{6, time.Hour + 4*time.Minute + 21*time.Second, 200 * time.Second, 300}, true},
+	// days remaining
+	{"Copying `db`.`table`:   1% 2+03:00:05 remain", 0, time.Minute,
+		CopyProgress{1, 51*time.Hour + 5*time.Second, time.Minute, 0}, true},
+	// not enough time has passed to estimate throughput
+	{"Copying `db`.`table`:  10% 00:20 remain", 1000000, 500 * time.Millisecond,
+		CopyProgress{10, 20 * time.Second, 500 * time.Millisecond, 0}, true},
+	// time remaining can't be parsed
+	{"Copying `db`.`table`:  10% soon remain", 0, time.Minute, CopyProgress{10, 0, time.Minute, 0}, true},
+	// percent can't be parsed
+	{"Copying `db`.`table`: abc", 0, time.Minute, CopyProgress{}, false},
+}
+
+func TestParseCopyProgress(t *testing.T) {
+	for _, tt := range parseCopyProgressTests {
+		migration := &Migration{TableRowsStart: tt.tableRowsStart}
+		actualProgress, actualOk := migration.parseCopyProgress(tt.line, tt.elapsed)
+		expectedProgress := tt.expectedProgress
+		if actualProgress != expectedProgress {
+			t.Errorf("progress = %v, want %v", actualProgress, expectedProgress)
+		}
+
+		expectedOk := tt.expectedOk
+		if actualOk != expectedOk {
+			t.Errorf("ok = %v, want %v", actualOk, expectedOk)
+		}
+	}
+}
+
 // test watching stderr during the copy step of a migration
 var watchMigrationCopyStderrTests = []struct {
 	stderr               string
@@ -745,10 +1480,10 @@ func TestWatchMigrationCopyStderr(t *testing.T) {
 
 		errChan := make(chan error, 1)
 		logChan := make(chan string, len(tt.expectedLogLines))
-		copyPercentChan := make(chan int, len(tt.expectedCopyPercents))
+		copyProgressChan := make(chan CopyProgress, len(tt.expectedCopyPercents))
 
 		migration := &Migration{}
-		go WatchMigrationCopyStderr(migration, stderrReader, copyPercentChan, errChan, logChan)
+		go WatchMigrationCopyStderr(migration, stderrReader, copyProgressChan, errChan, logChan)
 
 		actualError := <-errChan
 
@@ -759,8 +1494,8 @@ func TestWatchMigrationCopyStderr(t *testing.T) {
 		}
 		var actualCopyPercents []int
 		for i := 0; i < len(tt.expectedCopyPercents); i++ {
-			percent := <-copyPercentChan
-			actualCopyPercents = append(actualCopyPercents, percent)
+			progress := <-copyProgressChan
+			actualCopyPercents = append(actualCopyPercents, progress.Percent)
 		}
 
 		expectedError := tt.expectedError
@@ -163,6 +163,17 @@ func (restClient *restClient) Staged() (RestResponseItems, error) {
 	return response, nil
 }
 
+// Pinned gets an array of migrations that are pinned to a run host.
+func (restClient *restClient) Pinned(params map[string]string) (RestResponseItems, error) {
+	resource := "migrations/pinned"
+	response := RestResponseItems{}
+	err := restClient.get(resource, params, &response)
+	if err != nil {
+		return response, &RestError{"Pinned", err}
+	}
+	return response, nil
+}
+
 // Unstage unstages a migration.
 func (restClient *restClient) Unstage(params map[string]string) (RestResponseItem, error) {
 	resource := "migrations/unstage"
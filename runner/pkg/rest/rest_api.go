@@ -8,6 +8,12 @@ type RestClient interface {
 	// of a migration.
 	Staged() (RestResponseItems, error)
 
+	// Makes a GET request to the shift api at "/pinned".
+	// Returns result as array of mappings of strings to interfaces
+	// where each map in the array represents a migration that is
+	// pinned to the "run_host" in params.
+	Pinned(params map[string]string) (RestResponseItems, error)
+
 	// Makes a POST request to the shift api at "/unstage".
 	// Returns result as map of strings to interfaces, where keys and
 	// values describe all of the fields of a migration.
@@ -56,6 +56,7 @@ func initMigrationsJson() {
 
 	go func() {
 		http.HandleFunc("/api/v1/migrations/staged", httpJsonHandlerArray)
+		http.HandleFunc("/api/v1/migrations/pinned", httpJsonHandlerArray)
 		http.HandleFunc("/api/v1/migrations/unstage", httpJsonHandler)
 		http.HandleFunc("/api/v1/migrations/fail", httpJsonHandler)
 		http.HandleFunc("/api/v1/migrations/error", httpJsonHandler)
@@ -165,6 +166,30 @@ func TestStagedMigrations(t *testing.T) {
 	}
 }
 
+func TestPinnedMigrations(t *testing.T) {
+	initMigrationsJson()
+
+	client, err := New("http://localhost:12345/api/v1/", "", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	urlParams := make(map[string]string)
+	urlParams["run_host"] = "host1"
+	response, err := client.Pinned(urlParams)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	expectedArray := initMigrationArray()
+	for k, v := range response[0] {
+		actual := v
+		expected := expectedArray[0][k]
+		if expected != actual {
+			t.Errorf("response = %v, want %v", actual, expected)
+		}
+	}
+}
+
 func TestUnstageMigration(t *testing.T) {
 	initMigrationsJson()
 
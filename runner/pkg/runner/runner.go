@@ -53,6 +53,7 @@ var (
 	runMigrationDirect       = (*runner).runMigrationDirect
 	runMigrationDirectDrop   = (*runner).runMigrationDirectDrop
 	runMigrationPtOsc        = (*runner).runMigrationPtOsc
+	runMigrationNative       = (*runner).runMigrationNative
 	renameTablesStep         = (*runner).renameTablesStep
 	pauseMigrationStep       = (*runner).pauseMigrationStep
 	unstageRunnableMigration = (*runner).unstageRunnableMigration
@@ -60,6 +61,8 @@ var (
 	killMigration            = (*runner).killMigration
 	killPtOsc                = killPtOscProcess
 	killPtOscById            = killPtOscProcessById
+	ptOscRunning             = ptOscProcessRunning
+	recoverMigration         = (*runner).recoverMigration
 
 	// migration methods
 	SetupDbClient       = (*migration.Migration).SetupDbClient
@@ -73,6 +76,10 @@ var (
 	MoveToBlackHole     = (*migration.Migration).MoveToBlackHole
 	CleanUp             = (*migration.Migration).CleanUp
 	RunWriteQuery       = (*migration.Migration).RunWriteQuery
+	RecommendDdlMethod  = (*migration.Migration).RecommendDdlMethod
+	RunNativeDdl        = (*migration.Migration).RunNativeDdl
+	CheckForDataLoss    = (*migration.Migration).CheckForDataLoss
+	CountTruncatedRows  = (*migration.Migration).CountTruncatedRows
 
 	// define errors
 	ErrInvalidMigration = errors.New("runner: invalid migration")
@@ -84,6 +91,9 @@ var (
 	ErrGeneral          = errors.New("runner: there was an error with the runner")
 	ErrUnexpectedExit   = errors.New("runner: pt-osc died for an unexpected reason")
 	ErrUnkownStatus     = errors.New("runner: unkown status on the migration")
+
+	// the directories in the log directory that hold a migration's files
+	migrationDirRegex = regexp.MustCompile("^id-([0-9]+)$")
 )
 
 type runner struct {
@@ -232,6 +242,161 @@ func (runner *runner) killAndOfferMigrations() {
 	}
 }
 
+// recoverMigrations picks back up the migrations that were running on this
+// host when the runner last died. any pt-osc processes that were left behind
+// are killed first. then, for each migration the shift api still has pinned
+// to this host, it decides whether to resume it here, offer it up to other
+// hosts, or move it to the error step. the migrations that should be resumed
+// here are returned.
+func (runner *runner) recoverMigrations() []*migration.Migration {
+	recoveredMigrations := []*migration.Migration{}
+	stillRunning := runner.killOrphanedPtOscProcesses()
+
+	urlParams := map[string]string{"run_host": runner.Hostname}
+	pinnedMigrations, err := runner.RestClient.Pinned(urlParams)
+	if err != nil {
+		glog.Errorf("Failed to get migrations pinned to this host (error: %s)", err)
+		return recoveredMigrations
+	}
+
+	for _, pinnedMigration := range pinnedMigrations {
+		migrationStatusField, ok := pinnedMigration["status"].(float64)
+		migrationIdField, ok2 := pinnedMigration["id"].(float64)
+		if !ok || !ok2 {
+			glog.Errorf("Failed to get migration id and status for the following pinned migration: %v", pinnedMigration)
+			continue
+		}
+		mig := runner.newMigration(pinnedMigration, int(migrationIdField), int(migrationStatusField))
+
+		resume, err := recoverMigration(runner, mig, stillRunning[mig.Id])
+		if err != nil {
+			glog.Errorf("mig_id=%d: error recovering migration (error: %s)", mig.Id, err)
+			continue
+		}
+		if resume {
+			recoveredMigrations = append(recoveredMigrations, mig)
+		}
+	}
+	return recoveredMigrations
+}
+
+// killOrphanedPtOscProcesses scans the log directory for migrations that had
+// pt-osc running when the runner died, and kills any of those processes that
+// are still running, since nothing is watching their output or syncing their
+// state file anymore. it returns the ids of migrations whose pt-osc couldn't
+// be killed.
+func (runner *runner) killOrphanedPtOscProcesses() map[int]bool {
+	stillRunning := map[int]bool{}
+	dirs, err := ioutil.ReadDir(runner.LogDir)
+	if err != nil {
+		glog.Errorf("Failed to scan log directory '%s' for migrations (error: %s)", runner.LogDir, err)
+		return stillRunning
+	}
+
+	for _, dir := range dirs {
+		match := migrationDirRegex.FindStringSubmatch(dir.Name())
+		if !dir.IsDir() || match == nil {
+			continue
+		}
+		migrationId, _ := strconv.Atoi(match[1])
+		mig := &migration.Migration{Id: migrationId}
+		runner.setMigrationFiles(mig)
+
+		pid, err := mig.ReadPidFile()
+		if err != nil {
+			continue
+		}
+		if ptOscRunning(pid, mig.StateFile) {
+			glog.Infof("mig_id=%d: killing orphaned pt-osc (pid = %d).", migrationId, pid)
+			err = syscall.Kill(pid, ptOscKillSignal)
+			if err != nil {
+				glog.Errorf("mig_id=%d: error killing orphaned pt-osc (error: %s)", migrationId, err)
+				stillRunning[migrationId] = true
+				continue
+			}
+		}
+		os.Remove(mig.PidFile)
+	}
+	return stillRunning
+}
+
+// ptOscProcessRunning checks if pid belongs to a running pt-osc process that
+// is saving its state to stateFile, in case the pid has since been reused
+func ptOscProcessRunning(pid int, stateFile string) bool {
+	if err := syscall.Kill(pid, syscall.Signal(0)); err != nil && err != syscall.EPERM {
+		return false
+	}
+	cmdline, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/cmdline")
+	if err != nil {
+		// can't tell what the process is without /proc, so assume it's pt-osc
+		return true
+	}
+	return strings.Contains(string(cmdline), stateFile)
+}
+
+// recoverMigration decides what to do with a migration that was left pinned
+// to this host. a migration that was copying rows is resumed here with
+// --load-state if its state file is still on this host. if the state file
+// only made it to the api, the migration is offered up so any host can
+// resume it. otherwise the migration is moved to the error step. it returns
+// true if the migration should be resumed on this host.
+func (runner *runner) recoverMigration(currentMigration *migration.Migration, ptOscStillRunning bool) (bool, error) {
+	migrationId := strconv.Itoa(currentMigration.Id)
+	glog.Infof("mig_id=%d: recovering migration that was pinned to this host.", currentMigration.Id)
+
+	// only the copy step pins a migration to a host
+	if currentMigration.Status != migration.RunMigrationStatus {
+		_, err := runner.RestClient.UnpinRunHost(map[string]string{"id": migrationId})
+		return false, err
+	}
+
+	var errorMessage string
+	if ptOscStillRunning {
+		errorMessage = fmt.Sprintf("the runner on %s restarted while the migration was running, and "+
+			"the pt-osc process it left behind could not be killed", runner.Hostname)
+	} else {
+		// resume from the state file on this host. send it to the api first,
+		// since it is newer than the one there and will be retrieved again
+		// before pt-osc starts
+		if data, err := currentMigration.ReadStateFile(); err == nil && len(data) > 0 {
+			glog.Infof("mig_id=%d: resuming migration from state file '%s'.", currentMigration.Id, currentMigration.StateFile)
+			runner.postStateFile(currentMigration)
+			return true, nil
+		}
+
+		// let any host resume from the state file in the api
+		urlParams := map[string]string{
+			"migration_id": migrationId,
+			"file_type":    STATE_FILE_TYPE,
+		}
+		stateFile, err := runner.RestClient.GetFile(urlParams)
+		if err == nil && stateFile["contents"] != nil && len(stateFile["contents"].(string)) > 0 {
+			glog.Infof("mig_id=%d: no state file on this host. Offering migration.", currentMigration.Id)
+			urlParams = map[string]string{"id": migrationId}
+			_, err = runner.RestClient.UnpinRunHost(urlParams)
+			if err != nil {
+				return false, err
+			}
+			_, err = runner.RestClient.Offer(urlParams)
+			return false, err
+		}
+		errorMessage = fmt.Sprintf("the runner on %s restarted while the migration was running, and "+
+			"there is no pt-osc state file to resume from", runner.Hostname)
+	}
+
+	glog.Errorf("mig_id=%d: %s.", currentMigration.Id, errorMessage)
+	urlParams := map[string]string{
+		"id":            migrationId,
+		"error_message": errorMessage,
+	}
+	_, err := runner.RestClient.Error(urlParams)
+	if err != nil {
+		return false, err
+	}
+	_, err = runner.RestClient.UnpinRunHost(map[string]string{"id": migrationId})
+	return false, err
+}
+
 // getRunnableMigrations queries the shift api to get new/updated migrations.
 func (runner *runner) getRunnableMigrations() []*migration.Migration {
 	newMigrations := []*migration.Migration{}
@@ -292,57 +457,7 @@ func (runner *runner) unstageRunnableMigration(currentMigration rest.RestRespons
 			}
 		}
 
-		customOptions := make(map[string]string)
-		if val, ok := currentMigration["custom_options"]; ok && val != nil {
-			json.Unmarshal([]byte(currentMigration["custom_options"].(string)), &customOptions)
-		}
-
-		// log and statefiles are stored in log directory. ex for mig with id 7: /path/to/logs/statefile-id-7.txt
-		filesDir := runner.LogDir + "id-" + strconv.Itoa(int(migrationIdField)) + "/"
-		stateFile := filesDir + "statefile.txt"
-		logFile := filesDir + "ptosc-output.log"
-
-		mig := &migration.Migration{
-			Id:             migrationIdField,
-			Status:         migrationStatus,
-			Host:           currentMigration["host"].(string),
-			Port:           int(currentMigration["port"].(float64)),
-			Database:       currentMigration["database"].(string),
-			Table:          currentMigration["table"].(string),
-			DdlStatement:   currentMigration["ddl_statement"].(string),
-			FinalInsert:    currentMigration["final_insert"].(string),
-			FilesDir:       filesDir,
-			StateFile:      stateFile,
-			LogFile:        logFile,
-			PendingDropsDb: runner.PendingDropsDb,
-			EnableTrash:    runner.EnableTrash,
-			CustomOptions:  customOptions,
-		}
-
-		// some extra fields when we're not killing a migration
-		if (migrationStatus != migration.CancelStatus) && (migrationStatus != migration.PauseStatus) {
-			mig.RunType = int(currentMigration["runtype"].(float64))
-			mig.Mode = int(currentMigration["mode"].(float64))
-			mig.Action = int(currentMigration["action"].(float64))
-		}
-
-		// allow setting overrides for the host and db from config file.
-		// this is useful for safe testing in a staging environment
-		if runner.HostOverride != "" {
-			mig.Host = runner.HostOverride
-		}
-		if runner.PortOverride != 0 {
-			mig.Port = runner.PortOverride
-		}
-		if runner.DatabaseOverride != "" {
-			mig.Database = runner.DatabaseOverride
-		}
-
-		// if pending_drops database isn't defined, set it to be the same
-		// database that the migration is on.
-		if mig.PendingDropsDb == "" {
-			mig.PendingDropsDb = mig.Database
-		}
+		mig := runner.newMigration(currentMigration, migrationIdField, migrationStatus)
 
 		// only claim the migration if we successfully unstage it
 		urlParams := map[string]string{"id": strconv.Itoa(mig.Id)}
@@ -358,6 +473,72 @@ func (runner *runner) unstageRunnableMigration(currentMigration rest.RestRespons
 	return nil, nil
 }
 
+// newMigration builds a migration from a migration returned by the shift api
+func (runner *runner) newMigration(currentMigration rest.RestResponseItem, migrationIdField, migrationStatus int) *migration.Migration {
+	customOptions := make(map[string]string)
+	if val, ok := currentMigration["custom_options"]; ok && val != nil {
+		json.Unmarshal([]byte(currentMigration["custom_options"].(string)), &customOptions)
+	}
+
+	// the method recommended for running the ddl during the prep step
+	ddlMethod, _ := currentMigration["ddl_method"].(string)
+
+	// the row count from the prep step, used to estimate copy throughput
+	tableRowsStart, _ := currentMigration["table_rows_start"].(float64)
+
+	mig := &migration.Migration{
+		Id:             migrationIdField,
+		Status:         migrationStatus,
+		Host:           currentMigration["host"].(string),
+		Port:           int(currentMigration["port"].(float64)),
+		Database:       currentMigration["database"].(string),
+		Table:          currentMigration["table"].(string),
+		DdlStatement:   currentMigration["ddl_statement"].(string),
+		FinalInsert:    currentMigration["final_insert"].(string),
+		PendingDropsDb: runner.PendingDropsDb,
+		EnableTrash:    runner.EnableTrash,
+		CustomOptions:  customOptions,
+		DdlMethod:      ddlMethod,
+		TableRowsStart: int(tableRowsStart),
+	}
+	runner.setMigrationFiles(mig)
+
+	// some extra fields when we're not killing a migration
+	if (migrationStatus != migration.CancelStatus) && (migrationStatus != migration.PauseStatus) {
+		mig.RunType = int(currentMigration["runtype"].(float64))
+		mig.Mode = int(currentMigration["mode"].(float64))
+		mig.Action = int(currentMigration["action"].(float64))
+	}
+
+	// allow setting overrides for the host and db from config file.
+	// this is useful for safe testing in a staging environment
+	if runner.HostOverride != "" {
+		mig.Host = runner.HostOverride
+	}
+	if runner.PortOverride != 0 {
+		mig.Port = runner.PortOverride
+	}
+	if runner.DatabaseOverride != "" {
+		mig.Database = runner.DatabaseOverride
+	}
+
+	// if pending_drops database isn't defined, set it to be the same
+	// database that the migration is on.
+	if mig.PendingDropsDb == "" {
+		mig.PendingDropsDb = mig.Database
+	}
+	return mig
+}
+
+// setMigrationFiles sets the paths of the files that are kept for a migration.
+// they are stored in the log directory. ex for mig with id 7: /path/to/logs/id-7/statefile.txt
+func (runner *runner) setMigrationFiles(mig *migration.Migration) {
+	mig.FilesDir = runner.LogDir + "id-" + strconv.Itoa(mig.Id) + "/"
+	mig.StateFile = mig.FilesDir + "statefile.txt"
+	mig.LogFile = mig.FilesDir + "ptosc-output.log"
+	mig.PidFile = mig.FilesDir + "ptosc.pid"
+}
+
 // failMigration reports a failed migration to the shift database/ui.
 func (runner *runner) failMigration(migration *migration.Migration, errorMsg string) {
 	glog.Errorf("mig_id=%d: %s.", migration.Id, errorMsg)
@@ -432,11 +613,19 @@ func (runner *runner) prepMigrationStep(currentMigration *migration.Migration) e
 	if currentMigration.RunType != migration.SHORT_RUN {
 		// run a dry-run of pt-osc and make sure there were no errors.
 		// this will also validate the ddl statement.
-		var copyPercentChan chan int
-		_, err := execPtOsc(runner, currentMigration, runner.generatePtOscCommand, copyPercentChan, true)
+		var copyProgressChan chan migration.CopyProgress
+		_, err := execPtOsc(runner, currentMigration, runner.generatePtOscCommand, copyProgressChan, true)
 		if err != nil {
 			return err
 		}
+
+		// make sure copying the table won't silently throw away rows or values
+		if currentMigration.Action == migration.ALTER_ACTION {
+			err = runner.checkForDataLoss(currentMigration)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	var urlParams map[string]string
@@ -463,6 +652,22 @@ func (runner *runner) prepMigrationStep(currentMigration *migration.Migration) e
 			"table_size_start": tableStatsStart.TableSize,
 			"index_size_start": tableStatsStart.IndexSize,
 		}
+
+		// figure out if mysql can run the alter natively instead of
+		// having pt-osc copy the table
+		if currentMigration.Action == migration.ALTER_ACTION && currentMigration.RunType != migration.SHORT_RUN {
+			urlParams["ddl_method"] = runner.recommendDdlMethod(currentMigration)
+		}
+
+		// report how many rows have values that won't fit in a column the
+		// alter makes narrower
+		if currentMigration.Action == migration.ALTER_ACTION {
+			truncatedRows, err := CountTruncatedRows(currentMigration)
+			if err != nil {
+				return err
+			}
+			urlParams["truncated_rows"] = strconv.Itoa(truncatedRows)
+		}
 		_, err = runner.RestClient.Update(urlParams)
 		if err != nil {
 			return err
@@ -478,8 +683,36 @@ func (runner *runner) prepMigrationStep(currentMigration *migration.Migration) e
 	return nil
 }
 
+// checkForDataLoss fails a migration if copying the table would drop duplicate
+// rows for a new unique key or convert NULLs in a new NOT NULL column. Setting
+// the "allow_data_loss" custom option to "true" lets the migration continue.
+func (runner *runner) checkForDataLoss(currentMigration *migration.Migration) error {
+	err := CheckForDataLoss(currentMigration)
+	if _, ok := err.(migration.ErrDataLoss); ok && currentMigration.CustomOptions["allow_data_loss"] == "true" {
+		glog.Warningf("mig_id=%d: %s. Continuing because data loss is allowed.", currentMigration.Id, err)
+		return nil
+	}
+	return err
+}
+
+// recommendDdlMethod returns the method that an alter should be run with. The
+// "ddl_method" custom option can be set to "pt-osc" to always copy the table.
+// If the method can't be determined, pt-osc is used.
+func (runner *runner) recommendDdlMethod(currentMigration *migration.Migration) string {
+	if currentMigration.CustomOptions["ddl_method"] == migration.PTOSC_METHOD {
+		return migration.PTOSC_METHOD
+	}
+	ddlMethod, err := RecommendDdlMethod(currentMigration)
+	if err != nil {
+		glog.Errorf("mig_id=%d: error testing the ddl natively (error: %s). Falling back to pt-osc", currentMigration.Id, err)
+		return migration.PTOSC_METHOD
+	}
+	return ddlMethod
+}
+
 // runMigrationStep actually runs a migration. Based on the ddl statement, it
-// either runs it directly against the database, or with the ptosc tool
+// either runs it directly against the database, natively with an online
+// algorithm, or with the ptosc tool
 func (runner *runner) runMigrationStep(currentMigration *migration.Migration) (err error) {
 	if currentMigration.RunType == migration.SHORT_RUN {
 		defer unstagedMigrationsWaitGroup.Done()
@@ -488,11 +721,56 @@ func (runner *runner) runMigrationStep(currentMigration *migration.Migration) (e
 		} else {
 			return runMigrationDirect(runner, currentMigration)
 		}
+	} else if _, ok := migration.NATIVE_METHOD_TO_CLAUSE[currentMigration.DdlMethod]; ok {
+		defer unstagedMigrationsWaitGroup.Done()
+		return runMigrationNative(runner, currentMigration)
 	} else {
 		return runMigrationPtOsc(runner, currentMigration)
 	}
 }
 
+// runMigrationNative runs an alter directly against the database with the
+// method that was recommended during the prep step, so that no table copy is
+// needed. It then does all the remaining steps to fully complete the migration.
+func (runner *runner) runMigrationNative(currentMigration *migration.Migration) (err error) {
+	glog.Infof("mig_id=%d: running the alter natively (method = %s).", currentMigration.Id, currentMigration.DdlMethod)
+	err = RunNativeDdl(currentMigration, currentMigration.DdlMethod)
+	if err != nil {
+		return
+	}
+
+	// get the table stats
+	tableStatsEnd, err := CollectTableStats(currentMigration)
+	if err != nil {
+		return
+	}
+
+	// send the table stats to the api
+	urlParams := map[string]string{
+		"id":             strconv.Itoa(currentMigration.Id),
+		"table_rows_end": tableStatsEnd.TableRows,
+		"table_size_end": tableStatsEnd.TableSize,
+		"index_size_end": tableStatsEnd.IndexSize,
+	}
+	_, err = runner.RestClient.Update(urlParams)
+	if err != nil {
+		return
+	}
+
+	// run the final insert
+	if currentMigration.FinalInsert != "" {
+		err = RunWriteQuery(currentMigration, currentMigration.FinalInsert)
+		if err != nil {
+			return
+		}
+	}
+
+	// complete the migration
+	urlParams = map[string]string{"id": strconv.Itoa(currentMigration.Id)}
+	_, err = runner.RestClient.Complete(urlParams)
+	return
+}
+
 // runMigrationDirect runs a migration query directly against the database.
 // It then does all the remaining steps to fully complete the migration.
 func (runner *runner) runMigrationDirect(currentMigration *migration.Migration) (err error) {
@@ -586,8 +864,8 @@ func (runner *runner) runMigrationPtOsc(currentMigration *migration.Migration) (
 
 	defer runner.RestClient.UnpinRunHost(map[string]string{"id": migrationId})
 
-	copyPercentChan := make(chan int)
-	canceled, err := execPtOsc(runner, currentMigration, runner.generatePtOscCommand, copyPercentChan, false)
+	copyProgressChan := make(chan migration.CopyProgress)
+	canceled, err := execPtOsc(runner, currentMigration, runner.generatePtOscCommand, copyProgressChan, false)
 	if err != nil {
 		// if pt-osc ran into an error, move the migration to the "error" step instead
 		// of failing it. from the "error" step we will have the ability to try and
@@ -904,8 +1182,7 @@ type commandOptionGenerator func(*migration.Migration) (commandOptions []string)
 // there are different options for different steps
 func (runner *runner) generatePtOscCommand(currentMigration *migration.Migration) (commandOptions []string) {
 	// turn "Alter table t1 add column...." into "add column..."
-	alterPrefixRegex := regexp.MustCompile("^(?i)(ALTER\\s+TABLE\\s+.*?\\s+)")
-	alterStatement := alterPrefixRegex.ReplaceAllLiteralString(currentMigration.DdlStatement, "")
+	alterStatement := currentMigration.AlterClause()
 
 	// set options that don't exist with the defaults
 	var customOptions map[string]string
@@ -1005,7 +1282,7 @@ func (runner *runner) createAndRetrieveFiles(currentMigration *migration.Migrati
 
 // execPtOsc shells out and uses pt-osc to actually run a migration.
 func (runner *runner) execPtOsc(currentMigration *migration.Migration,
-	ptOscOptionGenerator commandOptionGenerator, copyPercentChan chan int, unstageDone bool) (bool, error) {
+	ptOscOptionGenerator commandOptionGenerator, copyProgressChan chan migration.CopyProgress, unstageDone bool) (bool, error) {
 
 	// unstageDone = whether or not unstagedMigrationsWaitGroup has already had .Done() called on it
 	defer func() {
@@ -1072,8 +1349,8 @@ func (runner *runner) execPtOsc(currentMigration *migration.Migration,
 		// setup a goroutine to continually update the % copied of the migration
 		fileRoutineWaitGroup.Add(1)
 		fileSyncWaitGroup.Add(1)
-		go runner.updateMigrationCopyPercentage(currentMigration, copyPercentChan, &fileRoutineWaitGroup)
-		go currentMigration.WatchMigrationCopyStderr(stderr, copyPercentChan, stderrErrChan, ptOscLogChan)
+		go runner.updateMigrationCopyPercentage(currentMigration, copyProgressChan, &fileRoutineWaitGroup)
+		go currentMigration.WatchMigrationCopyStderr(stderr, copyProgressChan, stderrErrChan, ptOscLogChan)
 	} else {
 		go currentMigration.WatchMigrationStderr(stderr, stderrErrChan, ptOscLogChan)
 	}
@@ -1081,6 +1358,10 @@ func (runner *runner) execPtOsc(currentMigration *migration.Migration,
 	// save the pid of the pt-osc process
 	currentMigration.Pid = cmd.Process.Pid
 	glog.Infof("mig_id=%d: pt-osc pid for status %d is %d.", currentMigration.Id, currentMigration.Status, currentMigration.Pid)
+	// also save it to disk, so that pt-osc can be found if the runner dies
+	if err := currentMigration.WritePidFile(currentMigration.Pid); err != nil {
+		glog.Warningf("mig_id=%d: error writing pid file '%s' (error: %s). Continuing anyway", currentMigration.Id, currentMigration.PidFile, err)
+	}
 
 	// add the migration id and pid to the running migration map
 	runningMigMutex.Lock()
@@ -1105,7 +1386,9 @@ func (runner *runner) execPtOsc(currentMigration *migration.Migration,
 	// get the exit status of the command. if it was sent a SIGKILL (most likely
 	// by another goroutine) we want to know because we will treat it differently
 	failed := false
-	if err := cmd.Wait(); err != nil {
+	err = cmd.Wait()
+	os.Remove(currentMigration.PidFile)
+	if err != nil {
 		if exiterr, ok := err.(*exec.ExitError); ok {
 			if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
 				exitSignal := status.Signal().String()
@@ -1125,13 +1408,13 @@ func (runner *runner) execPtOsc(currentMigration *migration.Migration,
 		if (stderrErr == nil) && (currentMigration.Status == migration.RunMigrationStatus) {
 			// wasn't killed. copy completed 100%
 			glog.Infof("mig_id=%d: updating migration with copy percentage of 100", currentMigration.Id)
-			copyPercentChan <- 100
+			copyProgressChan <- migration.CopyProgress{Percent: 100}
 		}
 	}
 
-	if copyPercentChan != nil {
-		glog.Infof("Closing copy percent channel")
-		close(copyPercentChan)
+	if copyProgressChan != nil {
+		glog.Infof("Closing copy progress channel")
+		close(copyProgressChan)
 	}
 
 	fileRoutineWaitGroup.Wait() // wait for go routines to finish
@@ -1150,16 +1433,24 @@ func (runner *runner) execPtOsc(currentMigration *migration.Migration,
 }
 
 // updateMigrationCopyPercentage watches a channel for a running migration and
-// sends the copy percentage completed (from the channel) to the shift api
-func (runner *runner) updateMigrationCopyPercentage(currentMigration *migration.Migration, copyPercentChan chan int, waitGroup *sync.WaitGroup) {
+// sends the copy progress (from the channel) to the shift api. the elapsed
+// time and throughput are only sent once they are known
+func (runner *runner) updateMigrationCopyPercentage(currentMigration *migration.Migration, copyProgressChan chan migration.CopyProgress, waitGroup *sync.WaitGroup) {
 	defer fileSyncWaitGroup.Done()
 	defer waitGroup.Done()
 	migrationId := strconv.Itoa(currentMigration.Id)
-	for copyPercentage := range copyPercentChan {
-		// send the table stats to the api
+	for copyProgress := range copyProgressChan {
+		// send the copy progress to the api
 		urlParams := map[string]string{
-			"id":              migrationId,
-			"copy_percentage": strconv.Itoa(copyPercentage),
+			"id":               migrationId,
+			"copy_percentage":  strconv.Itoa(copyProgress.Percent),
+			"copy_eta_seconds": strconv.Itoa(int(copyProgress.Remaining.Seconds())),
+		}
+		if copyProgress.Elapsed > 0 {
+			urlParams["copy_elapsed_seconds"] = strconv.Itoa(int(copyProgress.Elapsed.Seconds()))
+		}
+		if copyProgress.RowsPerSecond > 0 {
+			urlParams["copy_rows_per_second"] = strconv.Itoa(copyProgress.RowsPerSecond)
 		}
 		_, err := runner.RestClient.Update(urlParams)
 		if err != nil {
@@ -1177,6 +1468,15 @@ func Start(configFile string) error {
 
 	jobChannel := make(chan *migration.Migration)
 
+	// pick back up any migrations that were running when the runner last died
+	recoveredMigrations := migrationRunner.recoverMigrations()
+	unstagedMigrationsWaitGroup.Add(len(recoveredMigrations))
+	go func() {
+		for _, job := range recoveredMigrations {
+			jobChannel <- job
+		}
+	}()
+
 	go migrationRunner.watchForStopFile()
 
 	go migrationRunner.sendRunnableMigrationsToProcessor(jobChannel)
@@ -5,9 +5,11 @@ import (
 	"bytes"
 	"errors"
 	"os"
+	"os/exec"
 	"os/signal"
 	"reflect"
 	"regexp"
+	"strconv"
 	"sync"
 	"syscall"
 	"testing"
@@ -39,6 +41,7 @@ var (
 	writePayload    map[string]string
 	appendPayload   []map[string]string
 	ErrStaged       = &rest.RestError{"Staged", errors.New("there was an error")}
+	ErrPinned       = &rest.RestError{"Pinned", errors.New("there was an error")}
 	ErrUnstage      = &rest.RestError{"Unstage", errors.New("there was an error")}
 	ErrNextStep     = &rest.RestError{"NextStep", errors.New("there was an error")}
 	ErrUpdate       = &rest.RestError{"Update", errors.New("there was an error")}
@@ -67,6 +70,7 @@ func validTableStatsPayload(id, startOrEnd string) map[string]string {
 type stubRestClient struct {
 	// methods
 	staged       int
+	pinned       int
 	unstage      int
 	nextStep     int
 	update       int
@@ -92,6 +96,31 @@ func (restClient stubRestClient) Staged() (rest.RestResponseItems, error) {
 	}
 }
 
+func (restClient stubRestClient) Pinned(params map[string]string) (rest.RestResponseItems, error) {
+	payloadReceived = params
+	if restClient.pinned == 0 {
+		return []rest.RestResponseItem{}, nil
+	} else if restClient.pinned == 1 {
+		migration := map[string]interface{}{
+			"id":            float64(7),
+			"status":        float64(migration.RunMigrationStatus),
+			"host":          validHost,
+			"port":          float64(port),
+			"database":      database,
+			"table":         table,
+			"ddl_statement": validDdl1,
+			"final_insert":  "",
+			"runtype":       float64(migration.LONG_RUN),
+			"mode":          float64(migration.TABLE_MODE),
+			"action":        float64(migration.ALTER_ACTION),
+			"run_host":      hostname,
+		}
+		return []rest.RestResponseItem{migration, {"id": "bad"}}, nil
+	} else {
+		return nil, ErrPinned
+	}
+}
+
 func (restClient stubRestClient) Unstage(params map[string]string) (rest.RestResponseItem, error) {
 	payloadReceived = params
 	if restClient.unstage == 0 {
@@ -228,7 +257,7 @@ func (restClient stubRestClient) GetFile(params map[string]string) (rest.RestRes
 	if restClient.getFile == 0 {
 		return rest.RestResponseItem{}, nil
 	} else if restClient.getFile == 1 {
-		shiftFile := make(map[string]interface{})
+		shiftFile := map[string]interface{}{"contents": "state"}
 		return shiftFile, nil
 	} else {
 		return nil, ErrGetFile
@@ -318,6 +347,161 @@ func TestKillAndOfferMigrations(t *testing.T) {
 	}
 }
 
+// tests for recovering migrations that were running when the runner died
+var recoverMigrationsTests = []struct {
+	pinned             int
+	resume             bool
+	expectedMigrations []int
+	expectedPayload    map[string]string
+}{
+	// fail to get pinned migrations
+	{2, true, []int{}, map[string]string{"run_host": "host1"}},
+	// no pinned migrations
+	{0, true, []int{}, map[string]string{"run_host": "host1"}},
+	// migration is recovered some other way
+	{1, false, []int{}, map[string]string{"run_host": "host1"}},
+	// migration is resumed on this host
+	{1, true, []int{7}, map[string]string{"run_host": "host1"}},
+}
+
+func TestRecoverMigrations(t *testing.T) {
+	for _, tt := range recoverMigrationsTests {
+		payloadReceived = nil
+		currentRunner := initRunner(stubRestClient{pinned: tt.pinned}, "/nonexistent/", "", "")
+		currentRunner.Hostname = "host1"
+		recoverMigration = func(runner *runner, mig *migration.Migration, ptOscStillRunning bool) (bool, error) {
+			return tt.resume, nil
+		}
+
+		actualMigrations := []int{}
+		for _, mig := range currentRunner.recoverMigrations() {
+			actualMigrations = append(actualMigrations, mig.Id)
+		}
+		expectedMigrations := tt.expectedMigrations
+		if !reflect.DeepEqual(actualMigrations, expectedMigrations) {
+			t.Errorf("migrations = %v, want %v", actualMigrations, expectedMigrations)
+		}
+
+		expectedPayload := tt.expectedPayload
+		actualPayload := payloadReceived
+		if !reflect.DeepEqual(actualPayload, expectedPayload) {
+			t.Errorf("payload = %v, want %v", actualPayload, expectedPayload)
+		}
+	}
+	recoverMigration = (*runner).recoverMigration
+}
+
+// tests for deciding what to do with a migration that was left pinned to this host
+var recoverMigrationTests = []struct {
+	status            int
+	stateFile         string
+	ptOscStillRunning bool
+	restClient        stubRestClient
+	expectedResume    bool
+	expectedError     error
+	expectedPayload   map[string]string
+}{
+	// not on the copy step, so just unpin it
+	{migration.RenameTablesStatus, "", false, stubRestClient{}, false, nil, nil},
+	// fail to unpin it
+	{migration.RenameTablesStatus, "", false, stubRestClient{unpinRunHost: 2}, false, ErrUnpinRunHost, nil},
+	// pt-osc couldn't be killed
+	{migration.RunMigrationStatus, "state", true, stubRestClient{}, false, nil, map[string]string{"id": "7",
+		"error_message": "the runner on host1 restarted while the migration was running, and the pt-osc " +
+			"process it left behind could not be killed"}},
+	// resume from the local state file
+	{migration.RunMigrationStatus, "state", false, stubRestClient{}, true, nil, nil},
+	// offer it so it can be resumed from the state file in the api
+	{migration.RunMigrationStatus, "", false, stubRestClient{getFile: 1}, false, nil, map[string]string{"id": "7"}},
+	// fail to offer it
+	{migration.RunMigrationStatus, "", false, stubRestClient{getFile: 1, offer: 2}, false, ErrOffer,
+		map[string]string{"id": "7"}},
+	// no state file anywhere
+	{migration.RunMigrationStatus, "", false, stubRestClient{getFile: 2}, false, nil, map[string]string{"id": "7",
+		"error_message": "the runner on host1 restarted while the migration was running, and there is no " +
+			"pt-osc state file to resume from"}},
+	// fail to error it out
+	{migration.RunMigrationStatus, "", false, stubRestClient{err: 2}, false, ErrError, map[string]string{"id": "7",
+		"error_message": "the runner on host1 restarted while the migration was running, and there is no " +
+			"pt-osc state file to resume from"}},
+}
+
+func TestRecoverMigration(t *testing.T) {
+	logDir := os.TempDir() + "/shift-recover-migration-test/"
+	defer os.RemoveAll(logDir)
+	for _, tt := range recoverMigrationTests {
+		payloadReceived = nil
+		os.RemoveAll(logDir)
+		currentRunner := initRunner(tt.restClient, logDir, "", "")
+		currentRunner.Hostname = "host1"
+		mig := &migration.Migration{Id: 7, Status: tt.status}
+		currentRunner.setMigrationFiles(mig)
+		if tt.stateFile != "" {
+			_ = os.MkdirAll(mig.FilesDir, 0777)
+			_ = mig.WriteStateFile([]byte(tt.stateFile))
+		}
+
+		actualResume, actualError := currentRunner.recoverMigration(mig, tt.ptOscStillRunning)
+		expectedResume := tt.expectedResume
+		if actualResume != expectedResume {
+			t.Errorf("resume = %v, want %v", actualResume, expectedResume)
+		}
+
+		expectedError := tt.expectedError
+		if actualError != expectedError {
+			t.Errorf("error = %v, want %v", actualError, expectedError)
+		}
+
+		expectedPayload := tt.expectedPayload
+		actualPayload := payloadReceived
+		if !reflect.DeepEqual(actualPayload, expectedPayload) {
+			t.Errorf("payload = %v, want %v", actualPayload, expectedPayload)
+		}
+	}
+}
+
+// test killing pt-osc processes that were left running when the runner died
+func TestKillOrphanedPtOscProcesses(t *testing.T) {
+	logDir := os.TempDir() + "/shift-orphaned-pt-osc-test/"
+	defer os.RemoveAll(logDir)
+	os.RemoveAll(logDir)
+	currentRunner := initRunner(stubRestClient{}, logDir, "", "")
+
+	// a migration with a pt-osc process still running, one whose pt-osc
+	// already exited, and one whose pt-osc can't be killed
+	orphan := exec.Command("sleep", "30")
+	if err := orphan.Start(); err != nil {
+		t.Fatalf("error starting process: %s", err)
+	}
+	pids := map[int]int{7: orphan.Process.Pid, 8: 123456789, 9: -1}
+	for migrationId, pid := range pids {
+		mig := &migration.Migration{Id: migrationId}
+		currentRunner.setMigrationFiles(mig)
+		_ = os.MkdirAll(mig.FilesDir, 0777)
+		_ = mig.WritePidFile(pid)
+	}
+	ptOscRunning = func(pid int, stateFile string) bool {
+		return pid != 123456789
+	}
+
+	actualStillRunning := currentRunner.killOrphanedPtOscProcesses()
+	expectedStillRunning := map[int]bool{9: true}
+	if !reflect.DeepEqual(actualStillRunning, expectedStillRunning) {
+		t.Errorf("still running = %v, want %v", actualStillRunning, expectedStillRunning)
+	}
+
+	if err := orphan.Wait(); err == nil {
+		t.Errorf("orphaned process exited cleanly, want it killed")
+	}
+	for migrationId, expectedExists := range map[int]bool{7: false, 8: false, 9: true} {
+		_, err := os.Stat(logDir + "id-" + strconv.Itoa(migrationId) + "/ptosc.pid")
+		if actualExists := err == nil; actualExists != expectedExists {
+			t.Errorf("mig_id=%d: pid file exists = %v, want %v", migrationId, actualExists, expectedExists)
+		}
+	}
+	ptOscRunning = ptOscProcessRunning
+}
+
 // table driven test for getting runnable migrations
 // for the 'staged' method, 1 means return a normal response, anything else means return
 // an error.
@@ -723,33 +907,51 @@ var prepMigrationStepTests = []struct {
 	ptOscError        error
 	queryError        error
 	dryRunCreateError error
+	dataLossError     error
+	truncateError     error
 	expectedError     error
 	expectedPayload   map[string]string
 }{
 	// fail validating final insert
 	{0, 0, validDdl1, migration.LONG_RUN, migration.TABLE_MODE, migration.ALTER_ACTION,
-		nil, migration.ErrInvalidInsert{}, nil, nil, nil, migration.ErrInvalidInsert{}, nil},
+		nil, migration.ErrInvalidInsert{}, nil, nil, nil, nil, nil, migration.ErrInvalidInsert{}, nil},
 	// fail running pt-osc dry run
 	{0, 0, validDdl1, migration.LONG_RUN, migration.TABLE_MODE, migration.ALTER_ACTION,
-		nil, nil, migration.ErrPtOscUnexpectedStderr, nil, nil, migration.ErrPtOscUnexpectedStderr, nil},
+		nil, nil, migration.ErrPtOscUnexpectedStderr, nil, nil, nil, nil, migration.ErrPtOscUnexpectedStderr, nil},
+	// fail because the alter would lose data
+	{0, 0, validDdl1, migration.LONG_RUN, migration.TABLE_MODE, migration.ALTER_ACTION,
+		nil, nil, nil, nil, nil, migration.ErrDataLoss{DuplicateKeys: 2}, nil, migration.ErrDataLoss{DuplicateKeys: 2}, nil},
 	// fail running direct create dry run
 	{0, 0, validDirectDdl1, migration.SHORT_RUN, migration.TABLE_MODE, migration.CREATE_ACTION,
-		nil, nil, nil, nil, migration.ErrDryRunCreatesNew, migration.ErrDryRunCreatesNew, nil},
+		nil, nil, nil, nil, migration.ErrDryRunCreatesNew, nil, nil, migration.ErrDryRunCreatesNew, nil},
 	// fail collecting table status
 	{0, 0, validDirectDdl2, migration.SHORT_RUN, migration.TABLE_MODE, migration.DROP_ACTION,
-		&validTableStats, nil, nil, migration.ErrQueryFailed{}, nil, migration.ErrQueryFailed{}, nil},
+		&validTableStats, nil, nil, migration.ErrQueryFailed{}, nil, nil, nil, migration.ErrQueryFailed{}, nil},
 	// fail updating the migration
 	{2, 0, validDdl1, migration.LONG_RUN, migration.TABLE_MODE, migration.ALTER_ACTION,
-		&validTableStats, nil, nil, nil, nil, ErrUpdate, validTableStatsPayload("7", "start")},
+		&validTableStats, nil, nil, nil, nil, nil, nil, ErrUpdate, map[string]string{
+			"id":               "7",
+			"table_rows_start": "5",
+			"table_size_start": "98",
+			"index_size_start": "32",
+			"ddl_method":       migration.INSTANT_METHOD,
+			"truncated_rows":   "3",
+		}},
+	// fail counting rows that the alter would truncate
+	{0, 0, validDdl1, migration.LONG_RUN, migration.TABLE_MODE, migration.ALTER_ACTION,
+		&validTableStats, nil, nil, nil, nil, nil, migration.ErrDataLossCheck, migration.ErrDataLossCheck, nil},
+	// fail updating the migration for a drop, which doesn't get a ddl method
+	{2, 0, validDirectDdl2, migration.SHORT_RUN, migration.TABLE_MODE, migration.DROP_ACTION,
+		&validTableStats, nil, nil, nil, nil, nil, nil, ErrUpdate, validTableStatsPayload("7", "start")},
 	// fail moving the migration to the next step
 	{0, 2, validDirectDdl1, migration.SHORT_RUN, migration.TABLE_MODE, migration.CREATE_ACTION,
-		nil, nil, nil, nil, nil, ErrNextStep, map[string]string{"id": "7"}},
+		nil, nil, nil, nil, nil, nil, nil, ErrNextStep, map[string]string{"id": "7"}},
 	// succeeed nocheckalter run
 	{0, 0, validDdl1, migration.NOCHECKALTER_RUN, migration.TABLE_MODE, migration.ALTER_ACTION,
-		&validTableStats, nil, nil, nil, nil, nil, map[string]string{"id": "7"}},
+		&validTableStats, nil, nil, nil, nil, nil, nil, nil, map[string]string{"id": "7"}},
 	// succeeed long run
 	{0, 0, validDdl1, migration.LONG_RUN, migration.TABLE_MODE, migration.ALTER_ACTION,
-		&validTableStats, nil, nil, nil, nil, nil, map[string]string{"id": "7"}},
+		&validTableStats, nil, nil, nil, nil, nil, nil, nil, map[string]string{"id": "7"}},
 }
 
 func TestPrepMigrationStep(t *testing.T) {
@@ -767,7 +969,7 @@ func TestPrepMigrationStep(t *testing.T) {
 		ValidateFinalInsert = func(*migration.Migration) error {
 			return tt.finalInsertError
 		}
-		execPtOsc = func(*runner, *migration.Migration, commandOptionGenerator, chan int, bool) (bool, error) {
+		execPtOsc = func(*runner, *migration.Migration, commandOptionGenerator, chan migration.CopyProgress, bool) (bool, error) {
 			return false, tt.ptOscError
 		}
 		DryRunCreatesNew = func(*migration.Migration) error {
@@ -776,6 +978,15 @@ func TestPrepMigrationStep(t *testing.T) {
 		CollectTableStats = func(*migration.Migration) (*migration.TableStats, error) {
 			return tt.tableStats, tt.queryError
 		}
+		RecommendDdlMethod = func(*migration.Migration) (string, error) {
+			return migration.INSTANT_METHOD, nil
+		}
+		CheckForDataLoss = func(*migration.Migration) error {
+			return tt.dataLossError
+		}
+		CountTruncatedRows = func(*migration.Migration) (int, error) {
+			return 3, tt.truncateError
+		}
 
 		expectedError := tt.expectedError
 		actualError := currentRunner.prepMigrationStep(mig)
@@ -791,46 +1002,131 @@ func TestPrepMigrationStep(t *testing.T) {
 	}
 }
 
+// tests for checking if an alter would lose data
+var checkForDataLossTests = []struct {
+	customOptions map[string]string
+	dataLossError error
+	expectedError error
+}{
+	// no data lost
+	{map[string]string{}, nil, nil},
+	// error running the check
+	{map[string]string{"allow_data_loss": "true"}, migration.ErrDataLossCheck, migration.ErrDataLossCheck},
+	// data would be lost
+	{map[string]string{}, migration.ErrDataLoss{NullValues: 3}, migration.ErrDataLoss{NullValues: 3}},
+	// data would be lost, but it's allowed
+	{map[string]string{"allow_data_loss": "true"}, migration.ErrDataLoss{NullValues: 3}, nil},
+}
+
+func TestCheckForDataLoss(t *testing.T) {
+	for _, tt := range checkForDataLossTests {
+		currentRunner := initRunner(stubRestClient{}, "", "", "")
+		mig := &migration.Migration{Id: 7, CustomOptions: tt.customOptions}
+		CheckForDataLoss = func(*migration.Migration) error {
+			return tt.dataLossError
+		}
+
+		expectedError := tt.expectedError
+		actualError := currentRunner.checkForDataLoss(mig)
+		if actualError != expectedError {
+			t.Errorf("error = %v, want %v", actualError, expectedError)
+		}
+	}
+}
+
+// tests for picking the method to run an alter with
+var recommendDdlMethodTests = []struct {
+	customOptions     map[string]string
+	ddlMethod         string
+	ddlMethodError    error
+	expectedDdlMethod string
+}{
+	// pt-osc is forced with a custom option
+	{map[string]string{"ddl_method": migration.PTOSC_METHOD}, migration.INSTANT_METHOD, nil, migration.PTOSC_METHOD},
+	// error testing the ddl falls back to pt-osc
+	{map[string]string{}, "", migration.ErrServerVersion, migration.PTOSC_METHOD},
+	// mysql can run the ddl inplace
+	{map[string]string{}, migration.INPLACE_METHOD, nil, migration.INPLACE_METHOD},
+	// mysql can run the ddl instantly
+	{nil, migration.INSTANT_METHOD, nil, migration.INSTANT_METHOD},
+}
+
+func TestRecommendDdlMethod(t *testing.T) {
+	for _, tt := range recommendDdlMethodTests {
+		currentRunner := initRunner(stubRestClient{}, "", "", "")
+		mig := &migration.Migration{Id: 7, CustomOptions: tt.customOptions}
+		RecommendDdlMethod = func(*migration.Migration) (string, error) {
+			return tt.ddlMethod, tt.ddlMethodError
+		}
+
+		expectedDdlMethod := tt.expectedDdlMethod
+		actualDdlMethod := currentRunner.recommendDdlMethod(mig)
+		if actualDdlMethod != expectedDdlMethod {
+			t.Errorf("ddl method = %v, want %v", actualDdlMethod, expectedDdlMethod)
+		}
+	}
+}
+
 // tests for running the run migration step
 var runMigrationStepTests = []struct {
 	ddlStatement         string
 	runType              int
 	mode                 int
 	action               int
+	ddlMethod            string
 	runDirectCreateError error
 	runDirectDropError   error
 	runPtOscError        error
+	runNativeError       error
 	expectedError        error
 }{
 	// fail to create direct
 	{
 		validDirectDdl1, migration.SHORT_RUN,
-		migration.TABLE_MODE, migration.CREATE_ACTION,
-		migration.ErrQueryFailed{}, nil, nil, migration.ErrQueryFailed{},
+		migration.TABLE_MODE, migration.CREATE_ACTION, "",
+		migration.ErrQueryFailed{}, nil, nil, nil, migration.ErrQueryFailed{},
 	},
 	// fail to drop direct
 	{
 		validDirectDdl2, migration.SHORT_RUN,
-		migration.TABLE_MODE, migration.DROP_ACTION,
-		nil, migration.ErrDirectDrop, nil, migration.ErrDirectDrop,
+		migration.TABLE_MODE, migration.DROP_ACTION, "",
+		nil, migration.ErrDirectDrop, nil, nil, migration.ErrDirectDrop,
 	},
 	// run ptosc fails
 	{
 		validDdl1, migration.LONG_RUN,
-		migration.TABLE_MODE, migration.ALTER_ACTION,
-		nil, nil, ErrPtOscExec, ErrPtOscExec,
+		migration.TABLE_MODE, migration.ALTER_ACTION, "",
+		nil, nil, ErrPtOscExec, nil, ErrPtOscExec,
 	},
 	// run successfully long run
 	{
 		validDdl1, migration.LONG_RUN,
-		migration.TABLE_MODE, migration.ALTER_ACTION,
-		nil, nil, nil, nil,
+		migration.TABLE_MODE, migration.ALTER_ACTION, "",
+		nil, nil, nil, nil, nil,
+	},
+	// run pt-osc when the recommended method is pt-osc
+	{
+		validDdl1, migration.LONG_RUN,
+		migration.TABLE_MODE, migration.ALTER_ACTION, migration.PTOSC_METHOD,
+		nil, nil, ErrPtOscExec, nil, ErrPtOscExec,
+	},
+	// run natively fails
+	{
+		validDdl1, migration.LONG_RUN,
+		migration.TABLE_MODE, migration.ALTER_ACTION, migration.INSTANT_METHOD,
+		nil, nil, nil, migration.ErrQueryFailed{}, migration.ErrQueryFailed{},
+	},
+	// run successfully natively
+	{
+		validDdl1, migration.LONG_RUN,
+		migration.TABLE_MODE, migration.ALTER_ACTION, migration.INPLACE_METHOD,
+		nil, nil, ErrPtOscExec, nil, nil,
 	},
 	// run successfully nocheckalter run
 	{
 		validDdl1, migration.NOCHECKALTER_RUN,
-		migration.TABLE_MODE, migration.ALTER_ACTION,
-		nil, nil, nil, nil,
+		migration.TABLE_MODE, migration.ALTER_ACTION, "",
+		nil, nil, nil, nil, nil,
 	},
 }
 
@@ -843,8 +1139,10 @@ func TestRunMigrationStep(t *testing.T) {
 			RunType:      tt.runType,
 			Mode:         tt.mode,
 			Action:       tt.action,
+			DdlMethod:    tt.ddlMethod,
 		}
 		currentRunner := initRunner(stubRestClient{}, "", "", "")
+		unstagedMigrationsWaitGroup.Add(1)
 		runMigrationDirect = func(*runner, *migration.Migration) error {
 			return tt.runDirectCreateError
 		}
@@ -852,8 +1150,12 @@ func TestRunMigrationStep(t *testing.T) {
 			return tt.runDirectDropError
 		}
 		runMigrationPtOsc = func(*runner, *migration.Migration) error {
+			unstagedMigrationsWaitGroup.Done()
 			return tt.runPtOscError
 		}
+		runMigrationNative = func(*runner, *migration.Migration) error {
+			return tt.runNativeError
+		}
 
 		expectedError := tt.expectedError
 		actualError := currentRunner.runMigrationStep(mig)
@@ -913,6 +1215,70 @@ func TestRunMigrationDirectCreate(t *testing.T) {
 	}
 }
 
+// tests for running an alter natively against the db, and validate
+// the payload sent to the shift api
+var runMigrationNativeTests = []struct {
+	update           int
+	complete         int
+	nativeDdlError   error
+	tableStatsError  error
+	finalInsertError error
+	expectedError    error
+	expectedPayload  map[string]string
+}{
+	// fail running the alter
+	{0, 0, migration.ErrQueryFailed{}, nil, nil, migration.ErrQueryFailed{}, nil},
+	// fail getting table stats
+	{0, 0, nil, migration.ErrQueryFailed{}, nil, migration.ErrQueryFailed{}, nil},
+	// fail updating the migration
+	{2, 0, nil, nil, nil, ErrUpdate, validTableStatsPayload("7", "end")},
+	// fail running the final insert
+	{0, 0, nil, nil, migration.ErrQueryFailed{}, migration.ErrQueryFailed{}, validTableStatsPayload("7", "end")},
+	// fail completing the migration
+	{0, 2, nil, nil, nil, ErrComplete, map[string]string{"id": "7"}},
+	// succeed
+	{0, 0, nil, nil, nil, nil, map[string]string{"id": "7"}},
+}
+
+func TestRunMigrationNative(t *testing.T) {
+	for _, tt := range runMigrationNativeTests {
+		payloadReceived = nil
+		currentRunner := initRunner(stubRestClient{update: tt.update, complete: tt.complete}, "", "", "")
+		mig := &migration.Migration{Id: 7, FinalInsert: finalInsert, DdlMethod: migration.INSTANT_METHOD}
+
+		var actualMethod string
+		RunNativeDdl = func(mig *migration.Migration, method string) error {
+			actualMethod = method
+			return tt.nativeDdlError
+		}
+		CollectTableStats = func(*migration.Migration) (*migration.TableStats, error) {
+			return &validTableStats, tt.tableStatsError
+		}
+		RunWriteQuery = func(mig *migration.Migration, query string, args ...interface{}) error {
+			if query == finalInsert {
+				return tt.finalInsertError
+			}
+			return nil
+		}
+
+		expectedError := tt.expectedError
+		actualError := currentRunner.runMigrationNative(mig)
+		if actualError != expectedError {
+			t.Errorf("error = %v, want %v", actualError, expectedError)
+		}
+
+		if actualMethod != migration.INSTANT_METHOD {
+			t.Errorf("method = %v, want %v", actualMethod, migration.INSTANT_METHOD)
+		}
+
+		expectedPayload := tt.expectedPayload
+		actualPayload := payloadReceived
+		if !reflect.DeepEqual(actualPayload, expectedPayload) {
+			t.Errorf("payload = %v, want %v", actualPayload, expectedPayload)
+		}
+	}
+}
+
 // tests for running a drop migration directly against the db, and
 // and validate the payload sent to the shift api
 var runMigrationDirectDropTests = []struct {
@@ -1023,7 +1389,7 @@ func TestRunMigrationPtOsc(t *testing.T) {
 		currentRunner := initRunner(stubRestClient{nextStep: tt.nextStep, update: tt.update, err: tt.errorOut}, "", "", "")
 		currentRunner.Hostname = "host"
 		mig := &migration.Migration{Id: 7}
-		execPtOsc = func(*runner, *migration.Migration, commandOptionGenerator, chan int, bool) (bool, error) {
+		execPtOsc = func(*runner, *migration.Migration, commandOptionGenerator, chan migration.CopyProgress, bool) (bool, error) {
 			return tt.canceled, tt.ptOscError
 		}
 
@@ -1424,7 +1790,7 @@ var execPtOscTests = []struct {
 	// execute without an error for a migration not on the runMigration step
 	{0, "", "sleep", []string{"0"}, false, false, false, nil, nil, nil, nil},
 	// execute without an error for a migration on the runMigration step
-	{3, "", "sleep", []string{"0"}, false, false, false, map[string]string{"id": "7", "copy_percentage": "100"}, nil, nil, nil},
+	{3, "", "sleep", []string{"0"}, false, false, false, map[string]string{"id": "7", "copy_percentage": "100", "copy_eta_seconds": "0"}, nil, nil, nil},
 	// execute with output
 	{0, "", "/bin/sh", []string{"testscript"}, false, false, false, nil, nil, []map[string]string{
 		map[string]string{"migration_id": "7", "file_type": "0", "contents": "stdout: test 1\n"},
@@ -1492,13 +1858,13 @@ func TestExecPtOsc(t *testing.T) {
 			}()
 		}
 
-		// setup channel for receiving the copy % complete of the migration
-		var copyPercentChan chan int
+		// setup channel for receiving the copy progress of the migration
+		var copyProgressChan chan migration.CopyProgress
 		if tt.expectedPayload != nil || mig.Status == migration.RunMigrationStatus {
-			copyPercentChan = make(chan int)
+			copyProgressChan = make(chan migration.CopyProgress)
 		}
 
-		actualCanceled, actualError := currentRunner.execPtOsc(mig, commandGenerator, copyPercentChan, true)
+		actualCanceled, actualError := currentRunner.execPtOsc(mig, commandGenerator, copyProgressChan, true)
 
 		// cleanup the pt-osc log file...hard coded for now
 		_ = os.Remove(tt.logDir + "id-7/ptosc-output.log")
@@ -1541,26 +1907,40 @@ func TestExecPtOsc(t *testing.T) {
 	}
 }
 
-// test updating the copy percentage of a migration
-func TestUpdateMigrationCopyPercentage(t *testing.T) {
-	payloadReceived = nil
-	copyPercentChan := make(chan int, 1)
-	migration := &migration.Migration{Id: 7}
-	copyPercentage := 38
-	currentRunner := initRunner(stubRestClient{nextStep: 1}, "", "", "")
-	var waitGroup sync.WaitGroup
+// tests for updating the copy progress of a migration
+var updateMigrationCopyPercentageTests = []struct {
+	copyProgress    migration.CopyProgress
+	expectedPayload map[string]string
+}{
+	// only the percent and time remaining are known
+	{migration.CopyProgress{Percent: 38, Remaining: 192 * time.Second},
+		map[string]string{"id": "7", "copy_percentage": "38", "copy_eta_seconds": "192"}},
+	// elapsed time and throughput are known too
+	{migration.CopyProgress{Percent: 45, Remaining: 3*time.Minute + 12*time.Second, Elapsed: 157 * time.Second,
+		RowsPerSecond: 2866}, map[string]string{"id": "7", "copy_percentage": "45", "copy_eta_seconds": "192",
+		"copy_elapsed_seconds": "157", "copy_rows_per_second": "2866"}},
+}
 
-	copyPercentChan <- copyPercentage
-	close(copyPercentChan)
-	fileSyncWaitGroup.Add(1)
-	waitGroup.Add(1)
-	currentRunner.updateMigrationCopyPercentage(migration, copyPercentChan, &waitGroup)
-	fileSyncWaitGroup.Wait()
-	waitGroup.Wait()
+func TestUpdateMigrationCopyPercentage(t *testing.T) {
+	for _, tt := range updateMigrationCopyPercentageTests {
+		payloadReceived = nil
+		copyProgressChan := make(chan migration.CopyProgress, 1)
+		migration := &migration.Migration{Id: 7}
+		currentRunner := initRunner(stubRestClient{nextStep: 1}, "", "", "")
+		var waitGroup sync.WaitGroup
+
+		copyProgressChan <- tt.copyProgress
+		close(copyProgressChan)
+		fileSyncWaitGroup.Add(1)
+		waitGroup.Add(1)
+		currentRunner.updateMigrationCopyPercentage(migration, copyProgressChan, &waitGroup)
+		fileSyncWaitGroup.Wait()
+		waitGroup.Wait()
 
-	expectedPayload := map[string]string{"id": "7", "copy_percentage": "38"}
-	actualPayload := payloadReceived
-	if !reflect.DeepEqual(actualPayload, expectedPayload) {
-		t.Errorf("actual = %v, want %v", actualPayload, expectedPayload)
+		expectedPayload := tt.expectedPayload
+		actualPayload := payloadReceived
+		if !reflect.DeepEqual(actualPayload, expectedPayload) {
+			t.Errorf("actual = %v, want %v", actualPayload, expectedPayload)
+		}
 	}
 }